@@ -1,29 +1,38 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/qfarm/qfarm"
+	"github.com/qfarm/qfarm/export"
+	"github.com/qfarm/qfarm/queue"
 	"github.com/qfarm/qfarm/redis"
 )
 
 // Service is an API service with Redis connection.
 type Service struct {
 	r *redis.Service
+	q *queue.Queue
 }
 
 // NewService creates new API service.
 func NewService(r *redis.Service) *Service {
-	return &Service{r: r}
+	return &Service{r: r, q: queue.New(r, queue.BuildQueue)}
 }
 
-// TriggerBuild adds build request to Redis pubsub.
+// TriggerBuild adds build request to the build queue.
 func (s *Service) TriggerBuild(w http.ResponseWriter, req *http.Request) {
 	dec := json.NewDecoder(req.Body)
 	build := new(qfarm.Build)
@@ -32,13 +41,265 @@ func (s *Service) TriggerBuild(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	repo := strings.TrimRight(build.Repo, "/")
+	// specific commit might be requested instead of ref
+	ref := qfarm.NormalizeRef(build.Ref)
+	if ref == "" {
+		ref = build.CommitHash
+	}
+
+	job, err := s.enqueue(strings.TrimRight(build.Repo, "/"), ref, "")
+	if err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeJSON(w, job); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// Coverage and issues uploads are kept for a week.
+const coverageUploadTTL = 7 * 24 * 60 * 60
+
+// maxUploadSize is a limit of the request body of uploads.
+const maxUploadSize = 32 << 20
+
+var fullCommitHash = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// UploadCoverage accepts coverage profile (form file "profile") and optionally 'go test -json'
+// output (form file "tests") produced outside of the worker for given repo and commit. Build of
+// the commit is triggered and uses uploaded data instead of running tests. Build is recorded as
+// a build of the ref, if given.
+func (s *Service) UploadCoverage(w http.ResponseWriter, req *http.Request) {
+	req.Body = http.MaxBytesReader(w, req.Body, maxUploadSize)
+	repo, commit := strings.TrimRight(req.URL.Query().Get("repo"), "/"), req.URL.Query().Get("commit")
+	if repo == "" || !fullCommitHash.MatchString(commit) {
+		writeErrJSON(w, errors.New("Repo and full commit hash should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	profile, err := formFile(req, "profile")
+	if err != nil {
+		writeErrJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if profile == nil || !bytes.HasPrefix(profile, []byte("mode:")) {
+		writeErrJSON(w, errors.New("Go coverage profile should be uploaded!"), http.StatusBadRequest)
+		return
+	}
+
+	tests, err := formFile(req, "tests")
+	if err != nil {
+		writeErrJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	data, err := json.Marshal(qfarm.CoverageUpload{Repo: repo, Commit: commit, Time: time.Now().UTC(), Profile: profile, Tests: tests})
+	if err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.r.Set(fmt.Sprintf("coverage-uploads:%s:%s", repo, commit), coverageUploadTTL, data); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// ref might have moved since the upload, so the uploaded commit is built
+	job, err := s.enqueue(repo, qfarm.NormalizeRef(req.URL.Query().Get("ref")), commit)
+	if err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeJSON(w, job); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// UploadIssues accepts results of a linter run outside of qfarm (form file "report") in SARIF
+// (format=sarif) or Checkstyle (format=checkstyle) format for given repo and commit. Issues are
+// imported by build of the commit which is triggered and recorded as a build of the ref, if given.
+// Linter name might be given, otherwise tool reported in the file is used.
+func (s *Service) UploadIssues(w http.ResponseWriter, req *http.Request) {
+	req.Body = http.MaxBytesReader(w, req.Body, maxUploadSize)
+	repo, commit := strings.TrimRight(req.URL.Query().Get("repo"), "/"), req.URL.Query().Get("commit")
+	if repo == "" || !fullCommitHash.MatchString(commit) {
+		writeErrJSON(w, errors.New("Repo and full commit hash should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	format := req.URL.Query().Get("format")
+	if format != qfarm.LinterFormatSARIF && format != qfarm.LinterFormatCheckstyle {
+		writeErrJSON(w, errors.New("Format should be one of: sarif, checkstyle!"), http.StatusBadRequest)
+		return
+	}
+
+	report, err := formFile(req, "report")
+	if err != nil {
+		writeErrJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if report == nil {
+		writeErrJSON(w, errors.New("Report should be uploaded!"), http.StatusBadRequest)
+		return
+	}
+	if format == qfarm.LinterFormatSARIF && !json.Valid(report) {
+		writeErrJSON(w, errors.New("SARIF log isn't valid JSON!"), http.StatusBadRequest)
+		return
+	}
+
+	data, err := json.Marshal(qfarm.IssuesUpload{
+		Repo:   repo,
+		Commit: commit,
+		Time:   time.Now().UTC(),
+		Linter: req.URL.Query().Get("linter"),
+		Format: format,
+		Data:   report,
+	})
+	if err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	key := fmt.Sprintf("issues-uploads:%s:%s", repo, commit)
+	if err := s.r.ListPush(key, data); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.r.Expire(key, coverageUploadTTL); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// ref might have moved since the upload, so the uploaded commit is built
+	job, err := s.enqueue(repo, qfarm.NormalizeRef(req.URL.Query().Get("ref")), commit)
+	if err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeJSON(w, job); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// formFile returns content of the uploaded file or nil if it hasn't been uploaded.
+func formFile(req *http.Request, name string) ([]byte, error) {
+	f, _, err := req.FormFile(name)
+	if err == http.ErrMissingFile {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
+// enqueue creates build job of the ref and adds it to the build queue. If commit is given, it's
+// built instead of the head of the ref.
+func (s *Service) enqueue(repo, ref, commit string) (*qfarm.Job, error) {
+	job, err := queue.NewJob(repo)
+	if err != nil {
+		return nil, err
+	}
+	job.Ref = ref
+	job.Commit = commit
+
+	if err := queue.AddJob(s.r, job); err != nil {
+		return nil, err
+	}
+
+	if err := s.q.Push(job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Job returns current state of the build job.
+func (s *Service) Job(w http.ResponseWriter, req *http.Request) {
+	job, err := queue.GetJob(s.r, mux.Vars(req)["id"])
+	if err != nil {
+		if err == redis.ErrNotFound {
+			writeErrJSON(w, errors.New("Job not found!"), http.StatusNotFound)
+			return
+		}
+
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeJSON(w, job); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// CancelJob aborts queued or running build job.
+func (s *Service) CancelJob(w http.ResponseWriter, req *http.Request) {
+	job, err := queue.GetJob(s.r, mux.Vars(req)["id"])
+	if err != nil {
+		if err == redis.ErrNotFound {
+			writeErrJSON(w, errors.New("Job not found!"), http.StatusNotFound)
+			return
+		}
+
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if job.State.Finished() {
+		writeErrJSON(w, fmt.Errorf("Job already %s!", job.State), http.StatusConflict)
+		return
+	}
+
+	if err := queue.CancelJob(s.r, job); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeJSON(w, job); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+}
 
-	if err := s.r.ListPush("test-q-list", repo); err != nil {
+// RepoJobs returns most recent build jobs of specified repository. Number of jobs might be
+// set with size parameter (default 10, at most 100).
+func (s *Service) RepoJobs(w http.ResponseWriter, req *http.Request) {
+	repo := req.URL.Query().Get("repo")
+	if repo == "" {
+		writeErrJSON(w, errors.New("Repo should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	sizeInt := 10
+	if size := req.URL.Query().Get("size"); size != "" {
+		var err error
+		sizeInt, err = strconv.Atoi(size)
+		if err != nil || sizeInt < 1 {
+			writeErrJSON(w, errors.New("Size should be a positive number!"), http.StatusBadRequest)
+			return
+		}
+		if sizeInt > queue.MaxRepoJobs {
+			sizeInt = queue.MaxRepoJobs
+		}
+	}
+
+	jobs, err := queue.RepoJobs(s.r, repo, sizeInt)
+	if err != nil {
 		writeErrJSON(w, err, http.StatusInternalServerError)
 		return
 	}
-	if err := s.r.Publish("test-q-channel", repo); err != nil {
+
+	if err := writeJSON(w, jobs); err != nil {
 		writeErrJSON(w, err, http.StatusInternalServerError)
 		return
 	}
@@ -73,7 +334,8 @@ func (s *Service) LastBuilds(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
-// LastRepoBuilds returns most recent builds among specified repository.
+// LastRepoBuilds returns most recent builds among specified repository. Builds might
+// be limited to single branch, tag or pull request with ref parameter.
 func (s *Service) LastRepoBuilds(w http.ResponseWriter, req *http.Request) {
 	repo := req.URL.Query().Get("repo")
 	if repo == "" {
@@ -81,7 +343,12 @@ func (s *Service) LastRepoBuilds(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	builds, err := s.r.ListGetLastElements("builds:"+repo, 10)
+	key := "builds:" + repo
+	if ref := req.URL.Query().Get("ref"); ref != "" {
+		key += ":" + qfarm.NormalizeRef(ref)
+	}
+
+	builds, err := s.r.ListGetLastElements(key, 10)
 	if err != nil {
 		writeErrJSON(w, err, http.StatusInternalServerError)
 		return
@@ -168,7 +435,405 @@ func (s *Service) Report(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
-// RepoIssues returns list of specified repo issues.
+// CoverageDiff returns patch coverage and coverage changes of specified build compared with
+// its base build. If min is given, response tells whether patch coverage reaches it, so
+// pull requests might be gated on it.
+func (s *Service) CoverageDiff(w http.ResponseWriter, req *http.Request) {
+	repo := req.URL.Query().Get("repo")
+	if repo == "" {
+		writeErrJSON(w, errors.New("Repo should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	buildNoInt, err := s.buildNo(repo, req)
+	if err != nil {
+		writeErrJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	min := 0.0
+	if m := req.URL.Query().Get("min"); m != "" {
+		min, err = strconv.ParseFloat(m, 64)
+		if err != nil {
+			writeErrJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	reportJson, err := s.r.Get(fmt.Sprintf("reports:%s:%d", repo, buildNoInt))
+	if err != nil {
+		if err == redis.ErrNotFound {
+			writeErrJSON(w, errors.New("Build not found!"), http.StatusNotFound)
+			return
+		}
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	var report qfarm.Report
+	if err := json.Unmarshal(reportJson, &report); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	gate := coverageGate{CoverageDiff: report.CoverageDiff, Min: min, Passed: true}
+	if gate.CoverageDiff == nil {
+		// first build of the ref, nothing to compare with
+		gate.CoverageDiff = &qfarm.CoverageDiff{}
+	} else if gate.PatchLinesNo > 0 {
+		gate.Passed = gate.PatchCoverage >= min
+	}
+
+	if err := writeJSON(w, gate); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+type coverageGate struct {
+	*qfarm.CoverageDiff
+	Min    float64 `json:"min"`
+	Passed bool    `json:"passed"`
+}
+
+// IssuesGate tells whether issues of specified build don't exceed max_errors (0 by default) and
+// max_warnings (no limit by default). In baseline mode only new issues are counted.
+func (s *Service) IssuesGate(w http.ResponseWriter, req *http.Request) {
+	repo := req.URL.Query().Get("repo")
+	if repo == "" {
+		writeErrJSON(w, errors.New("Repo should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	buildNoInt, err := s.buildNo(repo, req)
+	if err != nil {
+		writeErrJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	gate := issuesGate{MaxErrors: 0, MaxWarnings: -1}
+	for name, max := range map[string]*int{"max_errors": &gate.MaxErrors, "max_warnings": &gate.MaxWarnings} {
+		if m := req.URL.Query().Get(name); m != "" {
+			*max, err = strconv.Atoi(m)
+			if err != nil {
+				writeErrJSON(w, err, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	reportJson, err := s.r.Get(fmt.Sprintf("reports:%s:%d", repo, buildNoInt))
+	if err != nil {
+		if err == redis.ErrNotFound {
+			writeErrJSON(w, errors.New("Build not found!"), http.StatusNotFound)
+			return
+		}
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	var report qfarm.Report
+	if err := json.Unmarshal(reportJson, &report); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	gate.ErrorsNo, gate.WarningsNo = report.ErrorsNo, report.WarningsNo
+	if report.Baseline != nil {
+		gate.Baseline = true
+		gate.ErrorsNo, gate.WarningsNo = report.Baseline.NewErrorsNo, report.Baseline.NewWarningsNo
+	}
+	gate.Passed = (gate.MaxErrors < 0 || gate.ErrorsNo <= gate.MaxErrors) && (gate.MaxWarnings < 0 || gate.WarningsNo <= gate.MaxWarnings)
+
+	if err := writeJSON(w, gate); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+type issuesGate struct {
+	ErrorsNo    int  `json:"errorsNo"`
+	WarningsNo  int  `json:"warningsNo"`
+	MaxErrors   int  `json:"maxErrors"`
+	MaxWarnings int  `json:"maxWarnings"`
+	Baseline    bool `json:"baseline"`
+	Passed      bool `json:"passed"`
+}
+
+// IssuesDiff returns issues introduced, fixed and unchanged between build from and build to
+// (last build by default) of specified repo. If from isn't given, build to is compared with
+// the build before it (the first build is compared with no issues). Issues are matched by
+// fingerprints.
+func (s *Service) IssuesDiff(w http.ResponseWriter, req *http.Request) {
+	repo := req.URL.Query().Get("repo")
+	if repo == "" {
+		writeErrJSON(w, errors.New("Repo should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	diff := issuesDiff{}
+	if to := req.URL.Query().Get("to"); to != "" {
+		diff.To, err = strconv.Atoi(to)
+		if err != nil {
+			writeErrJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	} else {
+		diff.To, err = s.getLastBuildNo(repo)
+		if err != nil {
+			writeErrJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	diff.From = diff.To - 1
+	if from := req.URL.Query().Get("from"); from != "" {
+		diff.From, err = strconv.Atoi(from)
+		if err != nil {
+			writeErrJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	issues := make(map[int][]*qfarm.Issue)
+	for _, no := range []int{diff.From, diff.To} {
+		// there is no build before the first one
+		if no == 0 {
+			continue
+		}
+
+		ok, err := s.r.Exists(fmt.Sprintf("reports:%s:%d", repo, no))
+		if err != nil {
+			writeErrJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			writeErrJSON(w, fmt.Errorf("Build %d not found!", no), http.StatusNotFound)
+			return
+		}
+
+		loaded, err := export.LoadIssues(s.r, repo, no)
+		if err != nil {
+			writeErrJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		for i := range loaded {
+			issues[no] = append(issues[no], &loaded[i].Issue)
+		}
+	}
+
+	// builds fingerprinted with different versions are compared using the older one
+	versions := make(map[int]int)
+	for _, no := range []int{diff.From, diff.To} {
+		versions[no], err = qfarm.FingerprintsVersion(issues[no])
+		if err != nil {
+			writeErrJSON(w, fmt.Errorf("Issues of build %d can't be compared: %v", no, err), http.StatusConflict)
+			return
+		}
+	}
+
+	fromVersion, toVersion := versions[diff.From], versions[diff.To]
+	if fromVersion < toVersion {
+		err = qfarm.SetFingerprintsVersion(issues[diff.To], "", fromVersion)
+	} else if toVersion < fromVersion {
+		err = qfarm.SetFingerprintsVersion(issues[diff.From], "", toVersion)
+	}
+	if err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	diff.Introduced, diff.Unchanged, diff.Fixed = qfarm.CompareIssues(issues[diff.From], issues[diff.To])
+
+	if err := writeJSON(w, diff); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+type issuesDiff struct {
+	From       int            `json:"from"`
+	To         int            `json:"to"`
+	Introduced []*qfarm.Issue `json:"introduced"`
+	Fixed      []*qfarm.Issue `json:"fixed"`
+	Unchanged  []*qfarm.Issue `json:"unchanged"`
+}
+
+// Baseline returns baseline of specified repo. Returned baseline might be uploaded to another
+// qfarm instance.
+func (s *Service) Baseline(w http.ResponseWriter, req *http.Request) {
+	repo := strings.TrimRight(req.URL.Query().Get("repo"), "/")
+	if repo == "" {
+		writeErrJSON(w, errors.New("Repo should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	data, err := s.r.Get(qfarm.BaselineKey(repo))
+	if err != nil {
+		if err == redis.ErrNotFound {
+			writeErrJSON(w, errors.New("Baseline not found!"), http.StatusNotFound)
+			return
+		}
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if _, err := w.Write(data); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+	}
+}
+
+// SetBaseline records baseline of specified repo from issues of build no or from uploaded
+// baseline (form file "baseline", as returned by Baseline, issues should have fingerprints). Builds of repos with baseline mode
+// enabled score only issues which aren't in the baseline.
+func (s *Service) SetBaseline(w http.ResponseWriter, req *http.Request) {
+	repo := strings.TrimRight(req.URL.Query().Get("repo"), "/")
+	if repo == "" {
+		writeErrJSON(w, errors.New("Repo should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	base := qfarm.Baseline{Repo: repo, Time: time.Now().UTC()}
+	if no := req.URL.Query().Get("no"); no != "" {
+		buildNoInt, err := strconv.Atoi(no)
+		if err != nil {
+			writeErrJSON(w, err, http.StatusBadRequest)
+			return
+		}
+
+		issues, err := export.LoadIssues(s.r, repo, buildNoInt)
+		if err != nil {
+			writeErrJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		if len(issues) == 0 {
+			if ok, err := s.r.Exists(fmt.Sprintf("reports:%s:%d", repo, buildNoInt)); err != nil || !ok {
+				writeErrJSON(w, errors.New("Build not found!"), http.StatusNotFound)
+				return
+			}
+		}
+
+		base.BuildNo = buildNoInt
+		base.Issues = make([]*qfarm.Issue, 0, len(issues))
+		for i := range issues {
+			base.Issues = append(base.Issues, &issues[i].Issue)
+		}
+	} else {
+		data, err := formFile(req, "baseline")
+		if err != nil {
+			writeErrJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		if data == nil {
+			writeErrJSON(w, errors.New("Build no or baseline should be given!"), http.StatusBadRequest)
+			return
+		}
+
+		var uploaded qfarm.Baseline
+		if err := json.Unmarshal(data, &uploaded); err != nil {
+			writeErrJSON(w, err, http.StatusBadRequest)
+			return
+		}
+
+		for _, i := range uploaded.Issues {
+			// fingerprints depend on the code, so they can't be computed here
+			if i.Fingerprint == "" {
+				writeErrJSON(w, errors.New("Issues of baseline should have fingerprints!"), http.StatusBadRequest)
+				return
+			}
+			if v, _ := qfarm.ParseFingerprint(i.Fingerprint); v > qfarm.FingerprintVersion {
+				writeErrJSON(w, fmt.Errorf("Unsupported version of fingerprints: %d!", v), http.StatusBadRequest)
+				return
+			}
+			if i.Linter == nil {
+				i.Linter = new(qfarm.Linter)
+			}
+		}
+		base.Issues = uploaded.Issues
+	}
+
+	// issues of the baseline are compared with issues of builds fingerprinted with the same version
+	if _, err := qfarm.FingerprintsVersion(base.Issues); err != nil {
+		writeErrJSON(w, fmt.Errorf("Issues of baseline can't be compared: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	data, err := json.Marshal(base)
+	if err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.r.Set(qfarm.BaselineKey(repo), -1, data); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeJSON(w, base); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// DeleteBaseline removes baseline of specified repo, so all issues are scored again.
+func (s *Service) DeleteBaseline(w http.ResponseWriter, req *http.Request) {
+	repo := strings.TrimRight(req.URL.Query().Get("repo"), "/")
+	if repo == "" {
+		writeErrJSON(w, errors.New("Repo should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.r.Del(qfarm.BaselineKey(repo)); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Export returns results of specified build in given format, eg. coverage as Cobertura XML
+// or LCOV tracefile, test results as JUnit XML and issues as SARIF, Checkstyle or Code Climate report.
+func (s *Service) Export(w http.ResponseWriter, req *http.Request) {
+	repo, format := req.URL.Query().Get("repo"), req.URL.Query().Get("format")
+	if repo == "" {
+		writeErrJSON(w, errors.New("Repo should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	contentType, err := export.ContentType(format)
+	if err != nil {
+		writeErrJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	buildNoInt, err := s.buildNo(repo, req)
+	if err != nil {
+		writeErrJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var out bytes.Buffer
+	if err := export.Write(&out, s.r, repo, buildNoInt, format); err != nil {
+		if err == redis.ErrNotFound {
+			writeErrJSON(w, errors.New("Build not found!"), http.StatusNotFound)
+			return
+		}
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write(out.Bytes()); err != nil {
+		log.Printf("Can't write export of %s: %v", repo, err)
+	}
+}
+
+// RepoIssues returns list of specified repo issues. Issues might be filtered by severity
+// (filter=error, filter=warning or filter=info), filter=suppressed returns suppressed issues. In baseline
+// mode filter=new, filter=existing and filter=fixed return issues compared with the baseline.
 func (s *Service) RepoIssues(w http.ResponseWriter, req *http.Request) {
 	repo := req.URL.Query().Get("repo")
 	if repo == "" {
@@ -300,6 +965,136 @@ func (s *Service) RepoFiles(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// RepoTests returns results of tests of specified build. Tests might be filtered by package
+// and status and are sorted by duration (longest first) unless sort=name is given.
+func (s *Service) RepoTests(w http.ResponseWriter, req *http.Request) {
+	repo := req.URL.Query().Get("repo")
+	if repo == "" {
+		writeErrJSON(w, errors.New("Repo should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	buildNoInt, err := s.buildNo(repo, req)
+	if err != nil {
+		writeErrJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	sizeInt, skipInt := 50, 0
+	if size := req.URL.Query().Get("size"); size != "" {
+		sizeInt, err = strconv.Atoi(size)
+		if err != nil {
+			writeErrJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if skip := req.URL.Query().Get("skip"); skip != "" {
+		skipInt, err = strconv.Atoi(skip)
+		if err != nil {
+			writeErrJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	key := fmt.Sprintf("tests:%s:%d", repo, buildNoInt)
+	if status := req.URL.Query().Get("status"); status != "" {
+		key += ":" + status
+	}
+
+	data, err := s.r.SortedSetGetAllRev(key)
+	if err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	pkg := req.URL.Query().Get("package")
+	tests := make([]qfarm.TestResult, 0)
+	for _, b := range data {
+		var single qfarm.TestResult
+		if err := json.Unmarshal(b, &single); err != nil {
+			writeErrJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if pkg != "" && single.Package != pkg {
+			continue
+		}
+
+		tests = append(tests, single)
+	}
+
+	switch req.URL.Query().Get("sort") {
+	case "", "duration":
+		// sorted set is ranked by duration
+	case "name":
+		sort.Sort(qfarm.TestsByName(tests))
+	default:
+		writeErrJSON(w, errors.New("Sort should be one of: duration, name!"), http.StatusBadRequest)
+		return
+	}
+
+	if skipInt > len(tests) {
+		skipInt = len(tests)
+	}
+	tests = tests[skipInt:]
+	if sizeInt >= 0 && sizeInt < len(tests) {
+		tests = tests[:sizeInt]
+	}
+
+	if err := writeJSON(w, tests); err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+}
+
+// TestOutput returns single test of specified build together with its output.
+func (s *Service) TestOutput(w http.ResponseWriter, req *http.Request) {
+	repo, pkg, name := req.URL.Query().Get("repo"), req.URL.Query().Get("package"), req.URL.Query().Get("name")
+	if repo == "" || pkg == "" || name == "" {
+		writeErrJSON(w, errors.New("Repo, package and name should be set!"), http.StatusBadRequest)
+		return
+	}
+
+	buildNoInt, err := s.buildNo(repo, req)
+	if err != nil {
+		writeErrJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	data, err := s.r.SortedSetGetAllRev(fmt.Sprintf("tests:%s:%d", repo, buildNoInt))
+	if err != nil {
+		writeErrJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	for _, b := range data {
+		var single qfarm.TestResult
+		if err := json.Unmarshal(b, &single); err != nil {
+			writeErrJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if single.Package != pkg || single.Name != name {
+			continue
+		}
+
+		output, err := s.r.Get(qfarm.TestOutputKey(repo, buildNoInt, pkg, name))
+		if err != nil && err != redis.ErrNotFound {
+			writeErrJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		single.Output = string(output)
+
+		if err := writeJSON(w, single); err != nil {
+			writeErrJSON(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeErrJSON(w, errors.New("Test not found!"), http.StatusNotFound)
+}
+
 func (s *Service) Badge(w http.ResponseWriter, req *http.Request) {
 	repo := req.URL.Query().Get("repo")
 	if repo == "" {
@@ -380,6 +1175,16 @@ func writeJSON(w http.ResponseWriter, response interface{}) error {
 	return nil
 }
 
+// buildNo returns build number given in request or number of the last build of the repo.
+func (s *Service) buildNo(repo string, req *http.Request) (int, error) {
+	buildNo := req.URL.Query().Get("no")
+	if buildNo == "" {
+		return s.getLastBuildNo(repo)
+	}
+
+	return strconv.Atoi(buildNo)
+}
+
 func (s *Service) getLastBuildNo(repo string) (int, error) {
 	var build qfarm.Build
 	data, err := s.r.ListGetLast("builds:" + repo)
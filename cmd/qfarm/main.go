@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/qfarm/qfarm/export"
+	"github.com/qfarm/qfarm/redis"
+)
+
+var redisConn = flag.String("redis-conn", "redis:6379", "Redis connection string")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: qfarm [flags] <command> [command flags]\n\nCommands:\n")
+	fmt.Fprintf(os.Stderr, "  export    export results of the build (formats: %s)\n\nFlags:\n", strings.Join(export.Formats(), ", "))
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := redis.NewConfig().WithConnection(*redisConn)
+	r, err := redis.NewService(cfg)
+	if err != nil {
+		log.Fatalf("Can't create redis service: %v", err)
+	}
+
+	switch flag.Arg(0) {
+	case "export":
+		err = exportCmd(r, flag.Args()[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("%s: %v", flag.Arg(0), err)
+	}
+}
+
+func exportCmd(r *redis.Service, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	repo := fs.String("repo", "", "Repo identifier, eg. github.com/qfarm/qfarm")
+	no := fs.Int("no", 0, "Build number, last build of the repo if not set")
+	format := fs.String("format", export.FormatCobertura, "Export format")
+	output := fs.String("o", "", "Output file, standard output if not set")
+	fs.Parse(args)
+
+	if *repo == "" {
+		return fmt.Errorf("repo should be set")
+	}
+
+	buildNo := *no
+	if buildNo == 0 {
+		var err error
+		buildNo, err = export.LastBuildNo(r, *repo)
+		if err != nil {
+			return fmt.Errorf("can't find last build of %s: %v", *repo, err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return export.Write(w, r, *repo, buildNo, *format)
+}
@@ -33,6 +33,20 @@ func main() {
 	router.HandleFunc("/files/", as.RepoFiles).Methods("GET")
 	router.HandleFunc("/reports/", as.Report).Methods("GET")
 	router.HandleFunc("/badges/", as.Badge).Methods("GET")
+	router.HandleFunc("/coverage_diff/", as.CoverageDiff).Methods("GET")
+	router.HandleFunc("/issues_gate/", as.IssuesGate).Methods("GET")
+	router.HandleFunc("/issues_diff/", as.IssuesDiff).Methods("GET")
+	router.HandleFunc("/baseline/", as.Baseline).Methods("GET")
+	router.HandleFunc("/baseline/", as.SetBaseline).Methods("POST")
+	router.HandleFunc("/baseline/", as.DeleteBaseline).Methods("DELETE")
+	router.HandleFunc("/coverage_uploads/", as.UploadCoverage).Methods("POST")
+	router.HandleFunc("/export/", as.Export).Methods("GET")
+	router.HandleFunc("/issues_uploads/", as.UploadIssues).Methods("POST")
+	router.HandleFunc("/tests/", as.RepoTests).Methods("GET")
+	router.HandleFunc("/tests/output/", as.TestOutput).Methods("GET")
+	router.HandleFunc("/jobs", as.RepoJobs).Methods("GET")
+	router.HandleFunc("/jobs/{id}", as.Job).Methods("GET")
+	router.HandleFunc("/jobs/{id}/cancel", as.CancelJob).Methods("POST")
 
 	http.Handle("/", handlers.CORS()(router))
 	log.Printf("Starting to serve on %s", *listen)
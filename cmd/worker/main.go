@@ -3,6 +3,9 @@ package main
 import (
 	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/qfarm/qfarm/worker"
 )
@@ -23,6 +26,18 @@ func main() {
 		log.Fatalf("Can't initialize worker: %v", err)
 	}
 
+	// release jobs in progress on shutdown, so they don't wait for the visibility timeout
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		log.Printf("Got %v, stopping worker...", s)
+		if err := w.Stop(); err != nil {
+			log.Fatalf("Can't release jobs of worker: %v", err)
+		}
+		os.Exit(0)
+	}()
+
 	log.Printf("Worker created! Starting!")
 	if err := w.Run(); err != nil {
 		log.Fatalf("Can't initialize worker: %v", err)
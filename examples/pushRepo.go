@@ -3,18 +3,21 @@ package main
 import (
 	"flag"
 	"fmt"
+
+	"github.com/qfarm/qfarm/queue"
 	"github.com/qfarm/qfarm/redis"
 )
 
 var srv *redis.Service
 var (
-	repo  = flag.String("repo", "github.com/qfarm/bad-go-code", "Repo to analysis")
-	redis = flags.String("redis", "docker:6379", "Redis connection string")
+	repo      = flag.String("repo", "github.com/qfarm/bad-go-code", "Repo to analysis")
+	ref       = flag.String("ref", "", "Branch, tag, commit or pull request to analyse")
+	redisConn = flag.String("redis", "docker:6379", "Redis connection string")
 )
 
 func main() {
 	flag.Parse()
-	cfg := redis.NewConfig().WithConnection(redis).WithPassword("")
+	cfg := redis.NewConfig().WithConnection(*redisConn).WithPassword("")
 	var err error
 	srv, err = redis.NewService(cfg)
 	if err != nil {
@@ -22,31 +25,29 @@ func main() {
 		return
 	}
 
-	if err = pushRepo(*repo); err != nil {
+	id, err := pushRepo(*repo, *ref)
+	if err != nil {
 		fmt.Printf("pushRepo test failed: %v\n", err)
 		return
 	}
 
-	if err = publishRepo(*repo); err != nil {
-		fmt.Printf("publishRepo test failed: %v\n", err)
-		return
-	}
-
-	fmt.Printf("Worker notified about repo: %s\n", *repo)
+	fmt.Printf("Build job %s of repo %s queued\n", id, *repo)
 }
 
-func pushRepo(repo string) error {
-	if err := srv.ListPush("test-q-list", repo); err != nil {
-		return fmt.Errorf("Can't push value in redis: %v", err)
+func pushRepo(repo, ref string) (string, error) {
+	job, err := queue.NewJob(repo)
+	if err != nil {
+		return "", err
 	}
+	job.Ref = ref
 
-	return nil
-}
+	if err := queue.AddJob(srv, job); err != nil {
+		return "", fmt.Errorf("Can't store job in redis: %v", err)
+	}
 
-func publishRepo(repo string) error {
-	if err := srv.Publish("test-q-channel", repo); err != nil {
-		return fmt.Errorf("Can't push value in redis: %v", err)
+	if err := queue.New(srv, queue.BuildQueue).Push(job); err != nil {
+		return "", fmt.Errorf("Can't push job in redis: %v", err)
 	}
 
-	return nil
-}
\ No newline at end of file
+	return job.ID, nil
+}
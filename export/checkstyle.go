@@ -0,0 +1,62 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line        int    `xml:"line,attr"`
+	Column      int    `xml:"column,attr,omitempty"`
+	Severity    string `xml:"severity,attr"`
+	Message     string `xml:"message,attr"`
+	Source      string `xml:"source,attr"`
+	Fingerprint string `xml:"fingerprint,attr"`
+}
+
+// WriteCheckstyle writes issues as Checkstyle XML report. Linter is reported as a source of
+// the error. Checkstyle has no place for fingerprints, so they are added as extra attribute.
+func WriteCheckstyle(w io.Writer, issues []Issue) error {
+	report := checkstyleReport{Version: "5.0"}
+	var file *checkstyleFile
+	for _, i := range issues {
+		if file == nil || file.Name != i.RelPath() {
+			report.Files = append(report.Files, checkstyleFile{Name: i.RelPath()})
+			file = &report.Files[len(report.Files)-1]
+		}
+
+		file.Errors = append(file.Errors, checkstyleError{
+			Line:        i.Line,
+			Column:      i.Col,
+			Severity:    string(i.Severity),
+			Message:     strings.TrimSpace(i.Message),
+			Source:      i.Linter.String(),
+			Fingerprint: i.Fingerprint,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
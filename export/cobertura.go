@@ -0,0 +1,115 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"path"
+	"sort"
+)
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        float64            `xml:"line-rate,attr"`
+	BranchRate      float64            `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      float64            `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   float64         `xml:"line-rate,attr"`
+	BranchRate float64         `xml:"branch-rate,attr"`
+	Complexity float64         `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int   `xml:"number,attr"`
+	Hits   int64 `xml:"hits,attr"`
+}
+
+// WriteCobertura writes coverage as Cobertura XML report. Every file is reported as a class
+// of the package given by its directory.
+func WriteCobertura(w io.Writer, c *Coverage, timestamp int64) error {
+	report := coberturaCoverage{Version: "qfarm", Timestamp: timestamp, Sources: []string{c.Repo}}
+
+	// files of the package are grouped together, even if files of its subpackages come between them
+	files := append([]FileCoverage{}, c.Files...)
+	sort.Sort(byPackage(files))
+
+	var pkg *coberturaPackage
+	var pkgValid, pkgCovered int
+	for _, f := range files {
+		if pkg == nil || pkg.Name != path.Join(c.Repo, f.Package()) {
+			if pkg != nil {
+				pkg.LineRate = rate(pkgCovered, pkgValid)
+			}
+			report.Packages = append(report.Packages, coberturaPackage{Name: path.Join(c.Repo, f.Package())})
+			pkg = &report.Packages[len(report.Packages)-1]
+			pkgValid, pkgCovered = 0, 0
+		}
+
+		class := coberturaClass{Name: path.Base(f.Path), Filename: f.Path, LineRate: rate(f.Covered(), len(f.Lines))}
+		for _, l := range f.Lines {
+			class.Lines = append(class.Lines, coberturaLine{Number: l.Line, Hits: l.Hits})
+		}
+		pkg.Classes = append(pkg.Classes, class)
+
+		pkgValid += len(f.Lines)
+		pkgCovered += f.Covered()
+		report.LinesValid += len(f.Lines)
+		report.LinesCovered += f.Covered()
+	}
+	if pkg != nil {
+		pkg.LineRate = rate(pkgCovered, pkgValid)
+	}
+	report.LineRate = rate(report.LinesCovered, report.LinesValid)
+
+	if _, err := io.WriteString(w, xml.Header+`<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`+"\n"); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func rate(covered, valid int) float64 {
+	if valid == 0 {
+		return 0
+	}
+	return float64(covered) / float64(valid)
+}
+
+type byPackage []FileCoverage
+
+func (f byPackage) Len() int      { return len(f) }
+func (f byPackage) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f byPackage) Less(i, j int) bool {
+	if f[i].Package() != f[j].Package() {
+		return f[i].Package() < f[j].Package()
+	}
+	return f[i].Path < f[j].Path
+}
@@ -0,0 +1,127 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+)
+
+type codeClimateIssue struct {
+	Type        string              `json:"type"`
+	CheckName   string              `json:"check_name"`
+	Description string              `json:"description"`
+	Categories  []string            `json:"categories"`
+	Location    codeClimateLocation `json:"location"`
+	Severity    string              `json:"severity"`
+	Fingerprint string              `json:"fingerprint"`
+}
+
+type codeClimateLocation struct {
+	Path      string                `json:"path"`
+	Positions *codeClimatePositions `json:"positions,omitempty"`
+	Lines     *codeClimateLines     `json:"lines,omitempty"`
+}
+
+type codeClimatePositions struct {
+	Begin codeClimatePosition `json:"begin"`
+	End   codeClimatePosition `json:"end"`
+}
+
+type codeClimatePosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type codeClimateLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end"`
+}
+
+// Code Climate categories of issues reported by linters, style issues are the default.
+var codeClimateCategories = map[string]string{
+	"dupl":        "Duplication",
+	"gocyclo":     "Complexity",
+	"errcheck":    "Bug Risk",
+	"gotype":      "Bug Risk",
+	"ineffassign": "Bug Risk",
+	"race":        "Bug Risk",
+	"vet":         "Bug Risk",
+	"vetshadow":   "Bug Risk",
+	"deadcode":    "Clarity",
+	"structcheck": "Clarity",
+	"varcheck":    "Clarity",
+	"aligncheck":  "Performance",
+
+	// analyzers of go vet and extra analyzers
+	"assign":        "Bug Risk",
+	"atomic":        "Bug Risk",
+	"bools":         "Bug Risk",
+	"composites":    "Bug Risk",
+	"copylocks":     "Bug Risk",
+	"errorsas":      "Bug Risk",
+	"httpresponse":  "Bug Risk",
+	"ifaceassert":   "Bug Risk",
+	"loopclosure":   "Bug Risk",
+	"lostcancel":    "Bug Risk",
+	"nilfunc":       "Bug Risk",
+	"nilness":       "Bug Risk",
+	"printf":        "Bug Risk",
+	"shadow":        "Bug Risk",
+	"shift":         "Bug Risk",
+	"stdmethods":    "Bug Risk",
+	"stringintconv": "Bug Risk",
+	"structtag":     "Bug Risk",
+	"tests":         "Bug Risk",
+	"unmarshal":     "Bug Risk",
+	"unreachable":   "Bug Risk",
+	"unsafeptr":     "Bug Risk",
+	"unusedresult":  "Bug Risk",
+}
+
+// WriteCodeClimate writes issues as JSON array of Code Climate issues (format used by GitLab
+// code quality reports).
+func WriteCodeClimate(w io.Writer, issues []Issue) error {
+	out := make([]codeClimateIssue, 0, len(issues))
+	for _, i := range issues {
+		linter := i.Linter.String()
+		category, ok := codeClimateCategories[linter]
+		if !ok {
+			category = "Style"
+		}
+
+		loc := codeClimateLocation{Path: i.RelPath()}
+		if i.Col > 0 {
+			pos := codeClimatePosition{Line: i.Line, Column: i.Col}
+			loc.Positions = &codeClimatePositions{Begin: pos, End: pos}
+		} else {
+			loc.Lines = &codeClimateLines{Begin: i.Line, End: i.Line}
+		}
+
+		out = append(out, codeClimateIssue{
+			Type:        "issue",
+			CheckName:   linter,
+			Description: strings.TrimSpace(i.Message),
+			Categories:  []string{category},
+			Location:    loc,
+			Severity:    codeClimateSeverity(i.Severity),
+			Fingerprint: i.Fingerprint,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func codeClimateSeverity(s qfarm.Severity) string {
+	switch s {
+	case qfarm.Error:
+		return "major"
+	case qfarm.Warning:
+		return "minor"
+	default:
+		return "info"
+	}
+}
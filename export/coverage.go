@@ -0,0 +1,169 @@
+// Package export converts stored results of builds into formats understood by other tools.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+	"github.com/qfarm/qfarm/redis"
+)
+
+// Coverage holds line coverage of all files of the build.
+type Coverage struct {
+	Repo  string
+	No    int
+	Files []FileCoverage
+}
+
+// FileCoverage holds line coverage of single file.
+type FileCoverage struct {
+	// Path relative to the repo root
+	Path  string
+	Lines []LineHits
+}
+
+// LineHits holds number of executions of the line. Only lines with statements are listed.
+type LineHits struct {
+	Line int
+	Hits int64
+}
+
+// Package returns directory of the file relative to the repo root.
+func (f FileCoverage) Package() string {
+	if i := strings.LastIndex(f.Path, "/"); i >= 0 {
+		return f.Path[:i]
+	}
+	return "."
+}
+
+// Covered returns number of lines executed at least once.
+func (f FileCoverage) Covered() int {
+	covered := 0
+	for _, l := range f.Lines {
+		if l.Hits > 0 {
+			covered++
+		}
+	}
+	return covered
+}
+
+// LastBuildNo returns number of the last build of the repo.
+func LastBuildNo(r *redis.Service, repo string) (int, error) {
+	var build qfarm.Build
+	data, err := r.ListGetLast("builds:" + repo)
+	if err != nil {
+		return -1, err
+	}
+
+	if err := json.Unmarshal(data.([]byte), &build); err != nil {
+		return -1, err
+	}
+
+	return build.No, nil
+}
+
+// LoadReport loads report of the build.
+func LoadReport(r *redis.Service, repo string, no int) (*qfarm.Report, error) {
+	data, err := r.Get(fmt.Sprintf("reports:%s:%d", repo, no))
+	if err != nil {
+		return nil, err
+	}
+
+	var report qfarm.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// LoadCoverage loads coverage of the build from file tree stored by the worker.
+func LoadCoverage(r *redis.Service, repo string, no int) (*Coverage, error) {
+	keys, err := r.Keys(fmt.Sprintf("files:%s:%d:*", repo, no))
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, redis.ErrNotFound
+	}
+
+	nodes := make([]qfarm.Node, 0, len(keys))
+	for _, k := range keys {
+		data, err := r.Get(k)
+		if err != nil {
+			return nil, err
+		}
+
+		var node qfarm.Node
+		if err := json.Unmarshal(data, &node); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	return NewCoverage(repo, no, nodes), nil
+}
+
+// NewCoverage computes line coverage of files of the file tree.
+func NewCoverage(repo string, no int, nodes []qfarm.Node) *Coverage {
+	c := &Coverage{Repo: repo, No: no}
+	for _, n := range nodes {
+		if n.Dir || !strings.HasSuffix(n.Path, ".go") || len(n.Blocks) == 0 {
+			continue
+		}
+
+		c.Files = append(c.Files, FileCoverage{
+			Path:  strings.TrimPrefix(strings.TrimPrefix(n.Path, repo), "/"),
+			Lines: lineHits(n.Blocks),
+		})
+	}
+
+	sort.Sort(byPath(c.Files))
+	return c
+}
+
+// lineHits converts coverage blocks into hit counts of lines. Line shared by several blocks
+// (eg. closing brace of if statement) gets the highest count.
+func lineHits(blocks []qfarm.CoverBlock) []LineHits {
+	hits := make(map[int]int64)
+	for _, b := range blocks {
+		if b.NumStmt == 0 {
+			continue
+		}
+
+		end := b.End.Line
+		if b.End.Col <= 1 && end > b.Start.Line {
+			// block ends at the beginning of the line
+			end--
+		}
+
+		for l := b.Start.Line; l <= end; l++ {
+			if h, ok := hits[l]; !ok || b.Count > h {
+				hits[l] = b.Count
+			}
+		}
+	}
+
+	lines := make([]LineHits, 0, len(hits))
+	for l, h := range hits {
+		lines = append(lines, LineHits{Line: l, Hits: h})
+	}
+	sort.Sort(byLine(lines))
+
+	return lines
+}
+
+type byPath []FileCoverage
+
+func (f byPath) Len() int           { return len(f) }
+func (f byPath) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+func (f byPath) Less(i, j int) bool { return f[i].Path < f[j].Path }
+
+type byLine []LineHits
+
+func (l byLine) Len() int           { return len(l) }
+func (l byLine) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l byLine) Less(i, j int) bool { return l[i].Line < l[j].Line }
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/qfarm/qfarm/redis"
+)
+
+// ErrUnknownFormat is returned when requested export format isn't supported.
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Supported export formats.
+const (
+	FormatCobertura = "cobertura"
+	FormatLCOV      = "lcov"
+	FormatJUnit     = "junit"
+
+	FormatSARIF       = "sarif"
+	FormatCheckstyle  = "checkstyle"
+	FormatCodeClimate = "codeclimate"
+)
+
+var contentTypes = map[string]string{
+	FormatCobertura: "application/xml; charset=utf-8",
+	FormatLCOV:      "text/plain; charset=utf-8",
+	FormatJUnit:     "application/xml; charset=utf-8",
+
+	FormatSARIF:       "application/sarif+json",
+	FormatCheckstyle:  "application/xml; charset=utf-8",
+	FormatCodeClimate: "application/json; charset=utf-8",
+}
+
+// Formats returns names of all supported export formats.
+func Formats() []string {
+	formats := make([]string, 0, len(contentTypes))
+	for f := range contentTypes {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// ContentType returns HTTP content type of the format.
+func ContentType(format string) (string, error) {
+	ct, ok := contentTypes[format]
+	if !ok {
+		return "", ErrUnknownFormat
+	}
+	return ct, nil
+}
+
+// Write writes results of the build in given format.
+func Write(w io.Writer, r *redis.Service, repo string, no int, format string) error {
+	if _, err := ContentType(format); err != nil {
+		return err
+	}
+
+	report, err := LoadReport(r, repo, no)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatCobertura, FormatLCOV:
+		c, err := LoadCoverage(r, repo, no)
+		if err != nil {
+			return err
+		}
+
+		if format == FormatLCOV {
+			return WriteLCOV(w, c)
+		}
+		return WriteCobertura(w, c, time.Time(report.Time).UnixNano()/int64(time.Millisecond))
+
+	case FormatJUnit:
+		tests, err := LoadTests(r, repo, no)
+		if err != nil {
+			return err
+		}
+
+		return WriteJUnit(w, report, tests)
+
+	case FormatSARIF, FormatCheckstyle, FormatCodeClimate:
+		issues, err := LoadIssues(r, repo, no)
+		if err != nil {
+			return err
+		}
+
+		switch format {
+		case FormatSARIF:
+			return WriteSARIF(w, issues)
+		case FormatCheckstyle:
+			return WriteCheckstyle(w, issues)
+		default:
+			return WriteCodeClimate(w, issues)
+		}
+	}
+
+	return ErrUnknownFormat
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+	"github.com/qfarm/qfarm/redis"
+)
+
+// Issue is an issue of the build with its fingerprint.
+type Issue struct {
+	qfarm.Issue
+}
+
+// RelPath returns path of the issue relative to the repo root.
+func (i Issue) RelPath() string {
+	return strings.TrimPrefix(i.Path, "/")
+}
+
+// LoadIssues loads all issues of the build sorted by location.
+func LoadIssues(r *redis.Service, repo string, no int) ([]Issue, error) {
+	data, err := r.SortedSetGetAllRev(fmt.Sprintf("issues:%s:%d", repo, no))
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make([]qfarm.Issue, 0, len(data))
+	for _, b := range data {
+		var single qfarm.Issue
+		single.Linter = new(qfarm.Linter)
+		if err := json.Unmarshal(b, &single); err != nil {
+			return nil, err
+		}
+		issues = append(issues, single)
+	}
+
+	return NewIssues(issues), nil
+}
+
+// NewIssues sorts issues by location. Fingerprints of version 1 are computed, if issues were
+// stored without them.
+func NewIssues(issues []qfarm.Issue) []Issue {
+	out := make([]Issue, 0, len(issues))
+	missing := make([]*qfarm.Issue, 0)
+	for i := range issues {
+		if issues[i].Fingerprint == "" {
+			missing = append(missing, &issues[i])
+		}
+	}
+	// code of the build isn't available anymore, version 1 doesn't use it (and is always supported)
+	_ = qfarm.SetFingerprintsVersion(missing, "", 1)
+
+	for _, i := range issues {
+		out = append(out, Issue{Issue: i})
+	}
+	sort.Sort(byLocation(out))
+
+	return out
+}
+
+type byLocation []Issue
+
+func (i byLocation) Len() int      { return len(i) }
+func (i byLocation) Swap(a, b int) { i[a], i[b] = i[b], i[a] }
+func (i byLocation) Less(a, b int) bool {
+	if i[a].RelPath() != i[b].RelPath() {
+		return i[a].RelPath() < i[b].RelPath()
+	}
+	if i[a].Line != i[b].Line {
+		return i[a].Line < i[b].Line
+	}
+	if i[a].Col != i[b].Col {
+		return i[a].Col < i[b].Col
+	}
+	if i[a].Linter.String() != i[b].Linter.String() {
+		return i[a].Linter.String() < i[b].Linter.String()
+	}
+	return i[a].Message < i[b].Message
+}
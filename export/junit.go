@@ -0,0 +1,167 @@
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qfarm/qfarm"
+	"github.com/qfarm/qfarm/redis"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// LoadTests loads results of all tests of the build together with their output.
+func LoadTests(r *redis.Service, repo string, no int) ([]qfarm.TestResult, error) {
+	data, err := r.SortedSetGetAllRev(fmt.Sprintf("tests:%s:%d", repo, no))
+	if err != nil {
+		return nil, err
+	}
+
+	tests := make([]qfarm.TestResult, 0, len(data))
+	for _, b := range data {
+		var t qfarm.TestResult
+		if err := json.Unmarshal(b, &t); err != nil {
+			return nil, err
+		}
+
+		output, err := r.Get(qfarm.TestOutputKey(repo, no, t.Package, t.Name))
+		if err != nil && err != redis.ErrNotFound {
+			return nil, err
+		}
+		t.Output = string(output)
+
+		tests = append(tests, t)
+	}
+
+	return tests, nil
+}
+
+// WriteJUnit writes test results as JUnit XML report with one test suite per package. Tests with
+// subtests aren't reported, so every failure is reported once. Flaky tests are reported as passed
+// if build ignores flaky tests.
+func WriteJUnit(w io.Writer, report *qfarm.Report, tests []qfarm.TestResult) error {
+	parents := make(map[string]bool)
+	for _, t := range tests {
+		if t.Parent != "" {
+			parents[t.Package+"."+t.Parent] = true
+		}
+	}
+
+	sort.Sort(qfarm.TestsByName(tests))
+
+	var out junitTestSuites
+	var suite *junitTestSuite
+	var total, suiteTime float64
+	for _, t := range tests {
+		if parents[t.Package+"."+t.Name] {
+			continue
+		}
+
+		if suite == nil || suite.Name != t.Package {
+			if suite != nil {
+				suite.Time = seconds(suiteTime)
+			}
+			out.Suites = append(out.Suites, junitTestSuite{Name: t.Package, Timestamp: time.Time(report.Time).Format("2006-01-02T15:04:05")})
+			suite = &out.Suites[len(out.Suites)-1]
+			suiteTime = 0
+		}
+
+		tc := junitTestCase{ClassName: t.Package, Name: t.Name, Time: seconds(t.Time.Seconds()), SystemOut: t.Output}
+		switch {
+		case t.Status == qfarm.TestFailed && !(t.Flaky && report.Config.IgnoreFlaky):
+			tc.Failure = &junitFailure{Message: failureMessage(t), Type: "Failure", Body: t.Output}
+			suite.Failures++
+			out.Failures++
+		case t.Status == qfarm.TestSkipped:
+			tc.Skipped = &junitSkipped{}
+			suite.Skipped++
+			out.Skipped++
+		}
+
+		suite.TestCases = append(suite.TestCases, tc)
+		suite.Tests++
+		out.Tests++
+		suiteTime += t.Time.Seconds()
+		total += t.Time.Seconds()
+	}
+	if suite != nil {
+		suite.Time = seconds(suiteTime)
+	}
+	out.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// failureMessage returns first line of the test output reported by the test itself,
+// eg. "foo_test.go:12: expected 1, got 2".
+func failureMessage(t qfarm.TestResult) string {
+	for _, line := range strings.Split(t.Output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "=== ") || strings.HasPrefix(line, "--- ") {
+			continue
+		}
+		return line
+	}
+
+	if t.Flaky {
+		return "Flaky test failed"
+	}
+	return "Test failed"
+}
+
+func seconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
@@ -0,0 +1,24 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WriteLCOV writes coverage as LCOV tracefile. Paths of source files are relative to the repo root.
+func WriteLCOV(w io.Writer, c *Coverage) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range c.Files {
+		fmt.Fprintf(bw, "TN:\n")
+		fmt.Fprintf(bw, "SF:%s\n", f.Path)
+		for _, l := range f.Lines {
+			fmt.Fprintf(bw, "DA:%d,%d\n", l.Line, l.Hits)
+		}
+		fmt.Fprintf(bw, "LF:%d\n", len(f.Lines))
+		fmt.Fprintf(bw, "LH:%d\n", f.Covered())
+		fmt.Fprintf(bw, "end_of_record\n")
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,130 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifFingerprint returns key and value of partial fingerprint of the issue. Key contains
+// version of the fingerprint, eg. qfarm/v2.
+func sarifFingerprint(fp string) (string, string) {
+	version, hash := qfarm.ParseFingerprint(fp)
+	return fmt.Sprintf("qfarm/v%d", version), hash
+}
+
+// WriteSARIF writes issues as SARIF 2.1.0 log with single run. Linters are reported as rules.
+func WriteSARIF(w io.Writer, issues []Issue) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "qfarm", InformationURI: "https://github.com/qfarm/qfarm"}},
+		Results: make([]sarifResult, 0, len(issues)),
+	}
+
+	rules := make(map[string]bool)
+	for _, i := range issues {
+		linter := i.Linter.String()
+		if !rules[linter] {
+			rules[linter] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: linter, ShortDescription: sarifMessage{Text: "Issues reported by " + linter}})
+		}
+
+		loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: i.RelPath(), URIBaseID: "%SRCROOT%"}}
+		if i.Line > 0 {
+			loc.Region = &sarifRegion{StartLine: i.Line, StartColumn: i.Col}
+		}
+
+		fpKey, fp := sarifFingerprint(i.Fingerprint)
+		run.Results = append(run.Results, sarifResult{
+			RuleID:              linter,
+			Level:               sarifLevel(i.Severity),
+			Message:             sarifMessage{Text: strings.TrimSpace(i.Message)},
+			Locations:           []sarifLocation{{PhysicalLocation: loc}},
+			PartialFingerprints: map[string]string{fpKey: fp},
+		})
+	}
+	sort.Sort(byRuleID(run.Tool.Driver.Rules))
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}})
+}
+
+func sarifLevel(s qfarm.Severity) string {
+	switch s {
+	case qfarm.Error:
+		return "error"
+	case qfarm.Warning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+type byRuleID []sarifRule
+
+func (r byRuleID) Len() int           { return len(r) }
+func (r byRuleID) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r byRuleID) Less(i, j int) bool { return r[i].ID < r[j].ID }
@@ -1,9 +1,13 @@
 package qfarm
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -16,9 +20,62 @@ type Build struct {
 	Score      int       `json:"score"`
 	Time       time.Time `json:"time,omitempty"`
 	CommitHash string    `json:"commitHash,omitempty"`
+	Ref        string    `json:"ref,omitempty"`
 	Config     BuildCfg  `json:"config,omitempty"`
 }
 
+// Job represents single build request and tracks its progress.
+type Job struct {
+	ID      string    `json:"id"`
+	Repo    string    `json:"repo"`
+	Ref     string    `json:"ref,omitempty"`
+	Time    time.Time `json:"time"`
+	Updated time.Time `json:"updated"`
+
+	// Commit to build, if set Ref is only a label under which build is recorded
+	Commit string `json:"commit,omitempty"`
+
+	State   JobState `json:"state"`
+	BuildNo int      `json:"buildNo,omitempty"`
+	Worker  string   `json:"worker,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Report  *Report  `json:"report,omitempty"`
+}
+
+// JobState is a state of the build job.
+type JobState string
+
+// Build job states.
+const (
+	JobQueued      JobState = "queued"
+	JobDownloading JobState = "downloading"
+	JobLinting     JobState = "linting"
+	JobCoverage    JobState = "coverage"
+	JobStoring     JobState = "storing"
+	JobDone        JobState = "done"
+	JobFailed      JobState = "failed"
+	JobCancelled   JobState = "cancelled"
+)
+
+// Finished checks whether job reached one of its final states.
+func (s JobState) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled
+}
+
+var pullRequestRef = regexp.MustCompile(`^(?:#|pull/|pr/|refs/pull/)(\d+)(?:/head)?$`)
+
+// NormalizeRef converts ref requested by user into the name under which builds are recorded.
+// Pull request refs ("#12", "pr/12", "refs/pull/12/head") are recorded as "pull/12".
+func NormalizeRef(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if m := pullRequestRef.FindStringSubmatch(ref); m != nil {
+		return "pull/" + m[1]
+	}
+
+	ref = strings.TrimPrefix(ref, "refs/heads/")
+	return strings.TrimPrefix(ref, "refs/tags/")
+}
+
 // BuildCfg represents configuration of the build.
 type BuildCfg struct {
 	// Repo identifier eg. github.com/influxdata/influxdb
@@ -27,20 +84,219 @@ type BuildCfg struct {
 	// Project path
 	Path string `json:"path"`
 
+	// Branch, tag or pull request of the build, as recorded in builds of the ref
+	Ref string `json:"ref,omitempty" yaml:"-"`
+
+	// Build workspace path (see worker.Workspace)
+	Workspace string `json:"-" yaml:"-"`
+
+	// Go modules found in the repo, empty for GOPATH based repos
+	Modules []Module `json:"modules,omitempty" yaml:"-"`
+
+	// GOROOT of the toolchain selected for the build
+	GoRoot string `json:"-" yaml:"-"`
+
 	// Skip directories
 	SkipDirs []string `json:"skipDirs"`
 
 	// Linters which should be used in analysis
 	Linters []string `json:"linters"`
 
-	// Enable vendoring support (skips 'vendor' directories and sets GO15VENDOREXPERIMENT=1).
+	// Linters defined by the repo, run only if worker config permits it
+	CustomLinters []CustomLinter `json:"customLinters,omitempty"`
+
+	// Analyzers (go/analysis) run in-process on packages loaded once for all of them
+	Analyzers []string `json:"analyzers"`
+
+	// Rules of issues which should be suppressed
+	Exclude []ExcludeRule `json:"exclude,omitempty"`
+
+	// Severities of issues of linters, keyed by linter name
+	Severities map[string]Severity `json:"severities,omitempty"`
+
+	// Severities of issues in matching paths, first matching rule wins over Severities
+	SeverityRules []SeverityRule `json:"severityRules,omitempty"`
+
+	// Parameters of linters, worker config is used for parameters which aren't set
+	LinterParams LinterParams `json:"linterParams,omitempty"`
+
+	// Baseline mode, only issues which aren't in the baseline of the repo are scored
+	Baseline bool `json:"baseline,omitempty"`
+
+	// Enable vendoring support (skips 'vendor' directories and sets GO15VENDOREXPERIMENT=1). GOPATH based repos only,
+	// Go modules use their vendor directory automatically.
 	Vendor bool `json:"vendor"`
 
-	// Go version
+	// Go version or version constraint, eg. "1.6", ">=1.5, <1.7"
 	Go string `json:"go"`
 
 	// Include test files
 	IncludeTests bool `json:"includeTests"`
+
+	// Seconds after which a single linter run is killed (can't exceed worker limit)
+	LinterTimeout int `json:"linterTimeout,omitempty"`
+
+	// Seconds after which the whole build is aborted (can't exceed worker limit)
+	BuildTimeout int `json:"buildTimeout,omitempty"`
+
+	// Number of times failed tests are re-run to detect flaky tests (can't exceed worker limit)
+	TestRetries int `json:"testRetries,omitempty"`
+
+	// Count flaky tests as passed, so they don't fail the build and don't lower the score
+	IgnoreFlaky bool `json:"ignoreFlaky"`
+
+	// Run tests with race detector, found data races are reported as issues
+	Race bool `json:"race"`
+
+	// Run tests in random order: "on", "off" or seed of the random order
+	Shuffle string `json:"shuffle,omitempty"`
+
+	// Number of times every test is run
+	Count int `json:"count,omitempty"`
+
+	// Measure coverage of all packages of the repo (or module) by tests of every package
+	CoverPkg bool `json:"coverPkg"`
+
+	// Coverage mode: "set", "count" or "atomic", default "set" ("atomic" when race detector is used)
+	CoverMode string `json:"coverMode,omitempty"`
+}
+
+// CustomLinter defines linter which isn't built into qfarm.
+type CustomLinter struct {
+	Name string `json:"name"`
+
+	// Command template, eg. "mylint -min {min_confidence} {tests=-t} .", available variables:
+	// duplthreshold, mincyclo, maxlinelength, min_confidence, min_occurrences, tests and path
+	Command string `json:"command"`
+
+	// Regexp with named groups path, line, col and message, predefined pattern
+	// (PATH:LINE:COL:MESSAGE, PATH:LINE:MESSAGE) or output format (SARIF, CHECKSTYLE)
+	Pattern string `json:"pattern"`
+
+	// Severity of all issues (info, warning or error), warning if empty
+	Severity Severity `json:"severity,omitempty"`
+
+	// Message of all issues, might contain named groups of the pattern, eg. "{message} in {function}"
+	MessageOverride string `json:"messageOverride,omitempty"`
+
+	// Package from which linter is installed with 'go get'
+	InstallFrom string `json:"installFrom,omitempty"`
+}
+
+// ExcludeRule suppresses issues which match all its conditions.
+type ExcludeRule struct {
+	// Names of linters, all linters if empty
+	Linters []string `json:"linters,omitempty"`
+
+	// Glob of the path relative to the repo root, glob without slash matches file name, eg. "*_test.go"
+	Path string `json:"path,omitempty"`
+
+	// Regexp of the issue message
+	Message string `json:"message,omitempty"`
+}
+
+// SeverityRule sets severity of issues which match all its conditions.
+type SeverityRule struct {
+	// Names of linters, all linters if empty
+	Linters []string `json:"linters,omitempty"`
+
+	// Glob of the path relative to the repo root, glob without slash matches file name, eg. "*_test.go"
+	Path string `json:"path,omitempty"`
+
+	Severity Severity `json:"severity"`
+}
+
+// LinterParams are parameters of linters set by the repo.
+type LinterParams struct {
+	// Report functions with cyclomatic complexity over N (gocyclo)
+	MinCyclo int `json:"minCyclo,omitempty"`
+
+	// Report lines longer than N (lll)
+	MaxLineLength int `json:"maxLineLength,omitempty"`
+
+	// Minimum confidence of golint issues
+	MinConfidence float64 `json:"minConfidence,omitempty"`
+
+	// Minimum occurrences of constants (goconst)
+	MinOccurrences int `json:"minOccurrences,omitempty"`
+
+	// Minimum token sequence as a clone (dupl)
+	DuplThreshold int `json:"duplThreshold,omitempty"`
+}
+
+// Module represents Go module found in the repo.
+type Module struct {
+	// Module path declared in go.mod
+	Path string `json:"path"`
+
+	// Module directory relative to the repo root
+	Dir string `json:"dir"`
+}
+
+// ModuleReport holds results of analysis of single Go module.
+type ModuleReport struct {
+	Module
+	Coverage   float64 `json:"coverage"`
+	TestsNo    int     `json:"testsNo"`
+	FailedNo   int     `json:"failedNo"`
+	PassedNo   int     `json:"passedNo"`
+	IssuesNo   int     `json:"issuesNo"`
+	ErrorsNo   int     `json:"errorsNo"`
+	WarningsNo int     `json:"warningsNo"`
+	InfosNo    int     `json:"infosNo"`
+}
+
+// CoverageUpload holds coverage profile and optional 'go test -json' output produced outside
+// of the worker for specified commit.
+type CoverageUpload struct {
+	Repo    string    `json:"repo"`
+	Commit  string    `json:"commit"`
+	Time    time.Time `json:"time"`
+	Profile []byte    `json:"profile"`
+	Tests   []byte    `json:"tests,omitempty"`
+}
+
+// IssuesUpload holds issues reported by linters run outside of the worker for specified commit.
+type IssuesUpload struct {
+	Repo   string    `json:"repo"`
+	Commit string    `json:"commit"`
+	Time   time.Time `json:"time"`
+
+	// Name of the linter, if empty, tool reported in the file is used
+	Linter string `json:"linter,omitempty"`
+
+	// LinterFormatSARIF or LinterFormatCheckstyle
+	Format string `json:"format"`
+	Data   []byte `json:"data"`
+}
+
+// CoverageDiff compares coverage of the build with coverage of its base build.
+type CoverageDiff struct {
+	// Build which the build is compared with, 0 if base commit has never been analyzed
+	BaseNo     int    `json:"baseNo,omitempty"`
+	BaseCommit string `json:"baseCommit"`
+
+	// Coverage of lines added or modified since the base commit. Only lines with statements are counted.
+	PatchCoverage  float64 `json:"patchCoverage"`
+	PatchLinesNo   int     `json:"patchLinesNo"`
+	PatchCoveredNo int     `json:"patchCoveredNo"`
+
+	// Change of total coverage since the base build
+	CoverageDelta float64 `json:"coverageDelta"`
+
+	Files []FileCoverageDiff `json:"files,omitempty"`
+}
+
+// FileCoverageDiff holds coverage changes of single file.
+type FileCoverageDiff struct {
+	Path           string  `json:"path"`
+	Coverage       float64 `json:"coverage"`
+	BaseCoverage   float64 `json:"baseCoverage"`
+	CoverageDelta  float64 `json:"coverageDelta"`
+	New            bool    `json:"new,omitempty"`
+	PatchLinesNo   int     `json:"patchLinesNo"`
+	PatchCoveredNo int     `json:"patchCoveredNo"`
+	UncoveredLines []int   `json:"uncoveredLines,omitempty"`
 }
 
 // CoverageReport holds info about coverage analysis of entire repo.
@@ -50,21 +306,72 @@ type CoverageReport struct {
 	TotalTestsNo  int
 	TotalPassedNo int
 	TotalFailedNo int
+	TotalFlakyNo  int
 	TotalTime     time.Duration
 	Failed        bool
 	Packages      []PackageReport
+	Races         []*Issue
 }
 
 // PackageReport holds info about coverage analysis of specified package.
 type PackageReport struct {
 	Name     string
+	Dir      string
+	Module   string
 	Coverage float64
+	NumStmt  int64
+	Covered  int64
 	Failed   bool
 	TestsNo  int
 	PassedNo int
 	FailedNo int
+	FlakyNo  int
 	Time     time.Duration
 	Files    map[string]CoverFileReport
+	Tests    []TestResult
+}
+
+// TestResult holds result of single test or subtest.
+type TestResult struct {
+	Package string        `json:"package"`
+	Name    string        `json:"name"`
+	Parent  string        `json:"parent,omitempty"`
+	Status  TestStatus    `json:"status"`
+	Time    time.Duration `json:"time"`
+	Output  string        `json:"output,omitempty"`
+
+	// Number of runs of the failed test, including re-runs
+	Attempts int `json:"attempts,omitempty"`
+
+	// Test outcome differs between re-runs or flips between builds
+	Flaky bool `json:"flaky,omitempty"`
+}
+
+// TestStatus is an outcome of the test.
+type TestStatus string
+
+// Test outcomes.
+const (
+	TestPassed  TestStatus = "pass"
+	TestFailed  TestStatus = "fail"
+	TestSkipped TestStatus = "skip"
+)
+
+// TestOutputKey returns key under which output of the test of the build is stored.
+func TestOutputKey(repo string, no int, pkg, name string) string {
+	return fmt.Sprintf("test-output:%s:%d:%s:%s", repo, no, pkg, name)
+}
+
+// TestsByName sorts test results by package and test name.
+type TestsByName []TestResult
+
+func (t TestsByName) Len() int      { return len(t) }
+func (t TestsByName) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t TestsByName) Less(i, j int) bool {
+	if t[i].Package != t[j].Package {
+		return t[i].Package < t[j].Package
+	}
+	return t[i].Name < t[j].Name
 }
 
 // CoverFileReport holds coverage report for single file.
@@ -101,6 +408,7 @@ type Node struct {
 	IssuesNo   int          `json:"issuesNo"`
 	ErrorsNo   int          `json:"errorsNo"`
 	WarningsNo int          `json:"warningsNo"`
+	InfosNo    int          `json:"infosNo"`
 	Issues     []*Issue     `json:"issues"`
 	Content    []byte       `json:"content"`
 }
@@ -116,9 +424,19 @@ type Linter struct {
 	MessageOverride  string   `json:"message_override,omitempty"`
 	EventType        string
 
+	// Format of the linter output: LinterFormatSARIF, LinterFormatCheckstyle or empty, if output
+	// is parsed with Pattern.
+	Format string `json:"format,omitempty"`
+
 	Regex *regexp.Regexp
 }
 
+// Formats of linter output which are parsed without pattern.
+const (
+	LinterFormatSARIF      = "sarif"
+	LinterFormatCheckstyle = "checkstyle"
+)
+
 // MarshalJSON marshals struct to JSON.
 func (l Linter) MarshalJSON() ([]byte, error) {
 	return json.Marshal(l.Name)
@@ -150,11 +468,21 @@ type Severity string
 
 // Linter message severity levels.
 const (
+	Info    Severity = "info"
 	Warning Severity = "warning"
 	Error   Severity = "error"
 )
 
+// Valid checks whether severity is one of known levels.
+func (s Severity) Valid() bool {
+	return s == Info || s == Warning || s == Error
+}
+
 func (s *Severity) Rank() int {
+	if *s == Info {
+		return 0
+	}
+
 	if *s == Warning {
 		return 1
 	}
@@ -174,6 +502,193 @@ type Issue struct {
 	Line     int      `json:"line"`
 	Col      int      `json:"col"`
 	Message  string   `json:"message"`
+
+	// All linters which reported the issue, if more than one did
+	Linters []string `json:"linters,omitempty"`
+
+	// Reason of suppression (nolint directive or exclude rule), suppressed issues aren't counted
+	Suppressed string `json:"suppressed,omitempty"`
+
+	// Fingerprint stays the same as long as the issue isn't fixed, even if lines of the file move
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// IssueNew or IssueExisting in baseline mode
+	Baseline string `json:"baseline,omitempty"`
+}
+
+// Issue state relative to the baseline.
+const (
+	IssueNew      = "new"
+	IssueExisting = "existing"
+)
+
+var messageNumber = regexp.MustCompile(`\d+`)
+
+// FingerprintVersion is a version of fingerprints computed by SetFingerprints. Fingerprints of
+// different versions never match, so issues have to be fingerprinted again with the same version
+// to be compared.
+const FingerprintVersion = 2
+
+// SetFingerprints computes fingerprints of issues. Fingerprint is computed from linter, path
+// relative to the root, normalized message, code around the issue returned by code func (which
+// might be nil) and number of the same issues found earlier in the file. It doesn't depend on
+// line numbers, so it survives unrelated changes of the file.
+func SetFingerprints(issues []*Issue, root string, code func(i *Issue) string) {
+	setFingerprints(issues, root, FingerprintVersion, code)
+}
+
+// SetFingerprintsVersion computes fingerprints of issues with older version, eg. to compare them
+// with issues fingerprinted before fingerprints changed. Version 1 doesn't use code around the issue.
+func SetFingerprintsVersion(issues []*Issue, root string, version int) error {
+	if version < 1 || version > FingerprintVersion {
+		return fmt.Errorf("unsupported version of fingerprints: %d", version)
+	}
+
+	setFingerprints(issues, root, version, nil)
+	return nil
+}
+
+func setFingerprints(issues []*Issue, root string, version int, code func(i *Issue) string) {
+	sorted := make([]*Issue, len(issues))
+	copy(sorted, issues)
+	sort.Sort(IssuesByLocation(sorted))
+
+	seen := make(map[string]int)
+	for _, i := range sorted {
+		path := strings.TrimPrefix(strings.TrimPrefix(i.Path, root), "/")
+		var key string
+		if version == 1 {
+			key = fmt.Sprintf("%s\x00%s\x00%s", i.Linter, path, strings.TrimSpace(i.Message))
+		} else {
+			key = fmt.Sprintf("%s\x00%s\x00%s", i.Linter, path, normalizeMessage(i.Message))
+			if code != nil {
+				key += "\x00" + code(i)
+			}
+		}
+
+		h := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", key, seen[key])))
+		i.Fingerprint = hex.EncodeToString(h[:])
+		if version > 1 {
+			i.Fingerprint = fmt.Sprintf("v%d:%s", version, i.Fingerprint)
+		}
+		seen[key]++
+	}
+}
+
+// ParseFingerprint returns version and hash of the fingerprint. Fingerprints without version
+// prefix are of version 1.
+func ParseFingerprint(fp string) (version int, hash string) {
+	if n := strings.Index(fp, ":"); n > 1 && fp[0] == 'v' {
+		if v, err := strconv.Atoi(fp[1:n]); err == nil {
+			return v, fp[n+1:]
+		}
+	}
+
+	return 1, fp
+}
+
+// FingerprintsVersion returns version of fingerprints of issues, which are fingerprinted together.
+// Issues fingerprinted with different versions can't be compared with other issues.
+func FingerprintsVersion(issues []*Issue) (int, error) {
+	version := 0
+	for _, i := range issues {
+		if i.Fingerprint == "" {
+			continue
+		}
+
+		v, _ := ParseFingerprint(i.Fingerprint)
+		if version != 0 && v != version {
+			return 0, fmt.Errorf("issues are fingerprinted with different versions: %d and %d", version, v)
+		}
+		version = v
+	}
+
+	if version == 0 {
+		return FingerprintVersion, nil
+	}
+	return version, nil
+}
+
+// normalizeMessage removes numbers (eg. line numbers or complexity) and repeated spaces from
+// the message.
+func normalizeMessage(msg string) string {
+	return strings.Join(strings.Fields(messageNumber.ReplaceAllString(msg, "N")), " ")
+}
+
+// CompareIssues matches issues with base issues by fingerprints. Returns issues which aren't
+// in base issues, issues which are in both and base issues which have been fixed.
+func CompareIssues(base, issues []*Issue) (introduced, unchanged, fixed []*Issue) {
+	// the same issue might be reported more than once
+	remaining := make(map[string]int)
+	for _, i := range base {
+		remaining[i.Fingerprint]++
+	}
+
+	introduced, unchanged, fixed = make([]*Issue, 0), make([]*Issue, 0), make([]*Issue, 0)
+	for _, i := range issues {
+		if remaining[i.Fingerprint] > 0 {
+			remaining[i.Fingerprint]--
+			unchanged = append(unchanged, i)
+		} else {
+			introduced = append(introduced, i)
+		}
+	}
+
+	for _, i := range base {
+		if remaining[i.Fingerprint] > 0 {
+			remaining[i.Fingerprint]--
+			fixed = append(fixed, i)
+		}
+	}
+
+	return introduced, unchanged, fixed
+}
+
+// IssuesByLocation sorts issues by path, line and column of the issue.
+type IssuesByLocation []*Issue
+
+func (i IssuesByLocation) Len() int      { return len(i) }
+func (i IssuesByLocation) Swap(a, b int) { i[a], i[b] = i[b], i[a] }
+func (i IssuesByLocation) Less(a, b int) bool {
+	if i[a].Path != i[b].Path {
+		return i[a].Path < i[b].Path
+	}
+	if i[a].Line != i[b].Line {
+		return i[a].Line < i[b].Line
+	}
+	if i[a].Col != i[b].Col {
+		return i[a].Col < i[b].Col
+	}
+	if i[a].Linter.String() != i[b].Linter.String() {
+		return i[a].Linter.String() < i[b].Linter.String()
+	}
+	return i[a].Message < i[b].Message
+}
+
+// BaselineKey returns key under which baseline of the repo is stored.
+func BaselineKey(repo string) string {
+	return "baseline:" + repo
+}
+
+// Baseline is a set of issues accepted in the repo. In baseline mode only issues which aren't
+// in the baseline are scored.
+type Baseline struct {
+	Repo string `json:"repo"`
+
+	// Build from which baseline was recorded, 0 if baseline was uploaded
+	BuildNo int       `json:"buildNo,omitempty"`
+	Time    time.Time `json:"time"`
+	Issues  []*Issue  `json:"issues"`
+}
+
+// BaselineDiff summarizes issues of the build compared with the baseline.
+type BaselineDiff struct {
+	BuildNo       int `json:"buildNo,omitempty"`
+	NewNo         int `json:"newNo"`
+	NewErrorsNo   int `json:"newErrorsNo"`
+	NewWarningsNo int `json:"newWarningsNo"`
+	ExistingNo    int `json:"existingNo"`
+	FixedNo       int `json:"fixedNo"`
 }
 
 // String returns formatted string.
@@ -187,21 +702,37 @@ func (i *Issue) String() string {
 
 // Reports stores information about whole analysis.
 type Report struct {
-	Repo       string    `json:"repo"`
-	No         int       `json:"no"`
-	Score      int       `json:"score"`
-	Time       JSONTime  `json:"time"`
-	Took       string    `json:"took"`
-	CommitHash string    `json:"commitHash"`
-	Config     BuildCfg  `json:"config"`
+	Repo       string   `json:"repo"`
+	No         int      `json:"no"`
+	Score      int      `json:"score"`
+	Time       JSONTime `json:"time"`
+	Took       string   `json:"took"`
+	CommitHash string   `json:"commitHash"`
+	Ref        string   `json:"ref,omitempty"`
+	Toolchain  string   `json:"toolchain,omitempty"`
+	Config     BuildCfg `json:"config"`
+
+	TimedOutLinters []string       `json:"timedOutLinters,omitempty"`
+	Modules         []ModuleReport `json:"modules,omitempty"`
+	FlakyTests      []TestResult   `json:"flakyTests,omitempty"`
+	CoverageDiff    *CoverageDiff  `json:"coverageDiff,omitempty"`
+
+	// Coverage has been uploaded instead of running tests on the worker
+	CoverageUploaded bool `json:"coverageUploaded,omitempty"`
+
+	// Issues compared with the baseline in baseline mode
+	Baseline *BaselineDiff `json:"baseline,omitempty"`
 
 	Coverage          float64 `json:"coverage"`
 	TestsNo           int     `json:"testsNo"`
 	FailedNo          int     `json:"failedNo"`
 	PassedNo          int     `json:"passedNo"`
+	FlakyNo           int     `json:"flakyNo"`
 	IssuesNo          int     `json:"issuesNo"`
 	ErrorsNo          int     `json:"errorsNo"`
 	WarningsNo        int     `json:"warningsNo"`
+	InfosNo           int     `json:"infosNo"`
+	SuppressedNo      int     `json:"suppressedNo"`
 	TechnicalDeptCost int     `json:"technicalDeptCost"`
 	TechnicalDeptTime string  `json:"technicalDeptTime"`
 }
@@ -234,4 +765,4 @@ func (t *JSONTime) String() string {
 	}
 
 	return time.Time(*t).Format(defaultDateFormat)
-}
\ No newline at end of file
+}
@@ -0,0 +1,158 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/qfarm/qfarm"
+	"github.com/qfarm/qfarm/redis"
+)
+
+// MaxRepoJobs is a number of the most recent jobs of the repo which are listed.
+const MaxRepoJobs = 100
+
+// SaveJob stores current state of the job.
+func SaveJob(r *redis.Service, job *qfarm.Job) error {
+	job.Updated = time.Now().UTC()
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return r.Set("job:"+job.ID, -1, data)
+}
+
+// AddJob stores new job and adds it to the list of jobs of the repo.
+func AddJob(r *redis.Service, job *qfarm.Job) error {
+	if err := SaveJob(r, job); err != nil {
+		return err
+	}
+
+	if err := r.ListPush("jobs:"+job.Repo, job.ID); err != nil {
+		return err
+	}
+
+	return r.ListTrim("jobs:"+job.Repo, MaxRepoJobs)
+}
+
+// GetJob returns job with the given ID. Returns redis.ErrNotFound if there is no such job.
+func GetJob(r *redis.Service, id string) (*qfarm.Job, error) {
+	data, err := r.Get("job:" + id)
+	if err != nil {
+		return nil, err
+	}
+
+	job := new(qfarm.Job)
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// RepoJobs returns most recent jobs of the repo, newest first.
+func RepoJobs(r *redis.Service, repo string, size int) ([]*qfarm.Job, error) {
+	ids, err := r.ListGetLastElements("jobs:"+repo, size)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*qfarm.Job, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		job, err := GetJob(r, string(ids[i]))
+		if err == redis.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// CancelChannel returns name of the pubsub channel on which worker receives IDs of jobs to cancel.
+func CancelChannel(worker string) string {
+	return fmt.Sprintf("workers:%s:cancel", worker)
+}
+
+// ClaimJob atomically assigns the job to the worker and moves it to the given state, unless
+// the job has been cancelled meanwhile. Returns false if so.
+func ClaimJob(r *redis.Service, job *qfarm.Job, worker string, state qfarm.JobState) (bool, error) {
+	claimed := false
+	err := r.Update("job:"+job.ID, func(data []byte) ([]byte, error) {
+		stored := new(qfarm.Job)
+		if err := json.Unmarshal(data, stored); err != nil {
+			return nil, err
+		}
+
+		claimed = stored.State != qfarm.JobCancelled
+		if !claimed {
+			return nil, nil
+		}
+
+		job.Worker = worker
+		job.State = state
+		job.Updated = time.Now().UTC()
+		return json.Marshal(job)
+	})
+
+	// job has been pushed to the queue without being stored
+	if err == redis.ErrNotFound {
+		job.Worker = worker
+		job.State = state
+		return true, SaveJob(r, job)
+	}
+
+	return claimed, err
+}
+
+// CancelJob requests cancellation of the job. Job which hasn't been claimed by any worker yet
+// is marked as cancelled right away, otherwise worker which owns the job is notified. Job is
+// updated with its current state.
+func CancelJob(r *redis.Service, job *qfarm.Job) error {
+	if err := r.Set("job:"+job.ID+":cancel", cancelTTL, "1"); err != nil {
+		return err
+	}
+
+	err := r.Update("job:"+job.ID, func(data []byte) ([]byte, error) {
+		stored := new(qfarm.Job)
+		if err := json.Unmarshal(data, stored); err != nil {
+			return nil, err
+		}
+		*job = *stored
+
+		if job.State != qfarm.JobQueued {
+			return nil, nil
+		}
+
+		job.State = qfarm.JobCancelled
+		job.Updated = time.Now().UTC()
+		return json.Marshal(job)
+	})
+	if err != nil {
+		return err
+	}
+
+	if job.State == qfarm.JobCancelled || job.Worker == "" {
+		return nil
+	}
+
+	if err := r.Publish(CancelChannel(job.Worker), job.ID); err != nil {
+		log.Printf("WARNING: Can't notify worker %s about cancelled job %s. Err: %v", job.Worker, job.ID, err)
+	}
+
+	return nil
+}
+
+// JobCancelled checks whether job has been cancelled.
+func JobCancelled(r *redis.Service, id string) (bool, error) {
+	return r.Exists("job:" + id + ":cancel")
+}
+
+// cancelTTL is a number of seconds for which cancel request is kept.
+const cancelTTL = 24 * 60 * 60
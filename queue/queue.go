@@ -0,0 +1,230 @@
+// Package queue provides reliable work queue built on top of Redis lists.
+//
+// Jobs are pushed to the head of the pending list and consumers atomically move them
+// into their own processing list. Job stays there until it's acknowledged, so a crash
+// of the consumer never loses it. Consumers refresh a heartbeat key while alive; jobs
+// kept in processing lists of consumers without heartbeat are moved back to the
+// pending list. Every delivery counts as an attempt and jobs which exceeded the retry
+// limit are moved to the dead-letter list.
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/qfarm/qfarm"
+	"github.com/qfarm/qfarm/redis"
+)
+
+// BuildQueue is a name of the queue with build requests.
+const BuildQueue = "test-q-list"
+
+// Default queue settings.
+const (
+	DefaultVisibilityTimeout = 60 * time.Second
+	DefaultMaxRetries        = 3
+)
+
+// Queue is a reliable job queue.
+type Queue struct {
+	redis *redis.Service
+	name  string
+
+	// VisibilityTimeout - time after which jobs of consumer which stopped sending heartbeats are requeued.
+	VisibilityTimeout time.Duration
+
+	// MaxRetries - number of deliveries after which job is moved to dead-letter list.
+	MaxRetries int
+}
+
+// New creates new queue with the given name.
+func New(r *redis.Service, name string) *Queue {
+	return &Queue{
+		redis:             r,
+		name:              name,
+		VisibilityTimeout: DefaultVisibilityTimeout,
+		MaxRetries:        DefaultMaxRetries,
+	}
+}
+
+// NewJob creates new job with unique ID for the given repo.
+func NewJob(repo string) (*qfarm.Job, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("can't generate job ID: %v", err)
+	}
+
+	return &qfarm.Job{ID: hex.EncodeToString(id), Repo: repo, Time: time.Now().UTC(), State: qfarm.JobQueued}, nil
+}
+
+// Push adds job to the queue.
+func (q *Queue) Push(job *qfarm.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return q.redis.ListPushFront(q.pendingKey(), data)
+}
+
+// Pop moves the oldest job to consumer's processing list and returns it. It waits up to timeout
+// for a job to appear. Returns redis.ErrNotFound when there was no job. Jobs which exceeded
+// the retry limit are moved to the dead-letter list and never returned.
+func (q *Queue) Pop(consumer string, timeout time.Duration) (*Delivery, error) {
+	for {
+		data, err := q.redis.ListBlockingMove(q.pendingKey(), q.processingKey(consumer), timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		d := &Delivery{queue: q, consumer: consumer, data: data}
+		if err := json.Unmarshal(data, &d.Job); err != nil {
+			log.Printf("WARNING: Can't decode job %q, moving to dead-letter list. Err: %v", data, err)
+			if err := q.redis.ListReplace(q.processingKey(consumer), data, q.deadKey(), data); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		d.Attempt, err = q.redis.HashIncr(q.attemptsKey(), d.Job.ID, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if d.Attempt > q.MaxRetries {
+			log.Printf("WARNING: Job %s exceeded retry limit (%d), moving to dead-letter list", d.Job.ID, q.MaxRetries)
+			if err := d.Reject(); err != nil {
+				return nil, err
+			}
+
+			d.Job.State = qfarm.JobFailed
+			d.Job.Error = fmt.Sprintf("exceeded retry limit (%d)", q.MaxRetries)
+			if err := SaveJob(q.redis, &d.Job); err != nil {
+				log.Printf("WARNING: Can't store state of job %s. Err: %v", d.Job.ID, err)
+			}
+			continue
+		}
+
+		return d, nil
+	}
+}
+
+// Heartbeat marks consumer as alive for the visibility timeout.
+func (q *Queue) Heartbeat(consumer string) error {
+	return q.redis.Set(q.consumerKey(consumer), int(q.VisibilityTimeout.Seconds()), time.Now().UTC().Format(time.RFC3339))
+}
+
+// Requeue moves jobs of consumers which stopped sending heartbeats back to the pending list.
+// Returns number of requeued jobs.
+func (q *Queue) Requeue() (int, error) {
+	keys, err := q.redis.Keys(q.processingKey("*"))
+	if err != nil {
+		return 0, err
+	}
+
+	requeued := 0
+	for _, k := range keys {
+		consumer := strings.TrimPrefix(k, q.processingKey(""))
+		alive, err := q.redis.Exists(q.consumerKey(consumer))
+		if err != nil {
+			return requeued, err
+		}
+
+		if alive {
+			continue
+		}
+
+		for {
+			_, err := q.redis.ListMove(k, q.pendingKey())
+			if err == redis.ErrNotFound {
+				break
+			}
+			if err != nil {
+				return requeued, err
+			}
+			requeued++
+		}
+		log.Printf("Consumer %s is gone, jobs requeued", consumer)
+	}
+
+	return requeued, nil
+}
+
+// Stop removes consumer heartbeat and moves its unacknowledged jobs back to the pending list.
+func (q *Queue) Stop(consumer string) error {
+	for {
+		_, err := q.redis.ListMove(q.processingKey(consumer), q.pendingKey())
+		if err == redis.ErrNotFound {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return q.redis.Del(q.consumerKey(consumer))
+}
+
+func (q *Queue) pendingKey() string {
+	return q.name
+}
+
+func (q *Queue) processingKey(consumer string) string {
+	return fmt.Sprintf("%s:processing:%s", q.name, consumer)
+}
+
+func (q *Queue) consumerKey(consumer string) string {
+	return fmt.Sprintf("%s:consumers:%s", q.name, consumer)
+}
+
+func (q *Queue) attemptsKey() string {
+	return q.name + ":attempts"
+}
+
+func (q *Queue) deadKey() string {
+	return q.name + ":dead"
+}
+
+// Delivery is a job fetched from the queue by a consumer. It has to be acknowledged,
+// retried or rejected once consumer is done with it.
+type Delivery struct {
+	Job     qfarm.Job
+	Attempt int
+
+	queue    *Queue
+	consumer string
+	data     []byte
+}
+
+// Ack marks job as successfully processed and removes it from the queue.
+func (d *Delivery) Ack() error {
+	if err := d.queue.redis.ListRemove(d.queue.processingKey(d.consumer), d.data); err != nil {
+		return err
+	}
+
+	return d.queue.redis.HashDel(d.queue.attemptsKey(), d.Job.ID)
+}
+
+// Retry puts job back to the queue. Job is moved to the dead-letter list if it already
+// used all of its attempts.
+func (d *Delivery) Retry() error {
+	if d.Attempt >= d.queue.MaxRetries {
+		return d.Reject()
+	}
+
+	return d.queue.redis.ListReplace(d.queue.processingKey(d.consumer), d.data, d.queue.pendingKey(), d.data)
+}
+
+// Reject moves job to the dead-letter list.
+func (d *Delivery) Reject() error {
+	if err := d.queue.redis.ListReplace(d.queue.processingKey(d.consumer), d.data, d.queue.deadKey(), d.data); err != nil {
+		return err
+	}
+
+	return d.queue.redis.HashDel(d.queue.attemptsKey(), d.Job.ID)
+}
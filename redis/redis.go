@@ -70,6 +70,10 @@ func (s *Service) Get(key string) ([]byte, error) {
 
 	reply, err := redis.Bytes(conn.Do("GET", key))
 	if err != nil {
+		if err == redis.ErrNil {
+			return nil, ErrNotFound
+		}
+
 		return nil, fmt.Errorf("error while fetching data from redis: %v", err)
 	}
 	log.Printf("Getting from redis: %s\n", key)
@@ -119,6 +123,129 @@ func (s *Service) Del(key string) error {
 	return nil
 }
 
+// Exists checks whether the key exists.
+func (s *Service) Exists(key string) (bool, error) {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	reply, err := redis.Bool(conn.Do("EXISTS", key))
+	if err != nil {
+		return false, fmt.Errorf("can't check key existence, key: %s, err: %v", key, err)
+	}
+
+	return reply, nil
+}
+
+// Expire sets ttl (in seconds) of the key.
+func (s *Service) Expire(key string, ttl int) error {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("EXPIRE", key, ttl); err != nil {
+		return fmt.Errorf("can't set ttl, key: %s, err: %v", key, err)
+	}
+
+	return nil
+}
+
+// HashIncr increments the number stored at field in the hash by given value.
+// Returns value after the increment.
+func (s *Service) HashIncr(key, field string, by int) (int, error) {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	reply, err := redis.Int(conn.Do("HINCRBY", key, field, by))
+	if err != nil {
+		return -1, fmt.Errorf("can't increment field %s, key: %s, err: %v", field, key, err)
+	}
+
+	return reply, nil
+}
+
+// HashGet returns value of the field stored in the hash.
+func (s *Service) HashGet(key, field string) ([]byte, error) {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	reply, err := redis.Bytes(conn.Do("HGET", key, field))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("can't get field %s, key: %s, err: %v", field, key, err)
+	}
+
+	return reply, nil
+}
+
+// HashSet sets value of the field in the hash.
+func (s *Service) HashSet(key, field string, data interface{}) error {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("HSET", key, field, data); err != nil {
+		return fmt.Errorf("can't set field %s, key: %s, err: %v", field, key, err)
+	}
+
+	return nil
+}
+
+// HashDel deletes field from the hash.
+func (s *Service) HashDel(key, field string) error {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("HDEL", key, field); err != nil {
+		return fmt.Errorf("can't delete field %s, key: %s, err: %v", field, key, err)
+	}
+
+	return nil
+}
+
+// Update atomically replaces value of the key with data returned by the update func, which gets
+// current value of the key. Nothing is stored when the func returns nil data. Update is retried
+// when the key has been modified meanwhile. Returns ErrNotFound when there is no such key.
+func (s *Service) Update(key string, update func(data []byte) ([]byte, error)) error {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	for {
+		if _, err := conn.Do("WATCH", key); err != nil {
+			return fmt.Errorf("can't watch key: %s, err: %v", key, err)
+		}
+
+		data, err := redis.Bytes(conn.Do("GET", key))
+		if err == nil {
+			data, err = update(data)
+		} else if err == redis.ErrNil {
+			err = ErrNotFound
+		}
+		if err != nil || data == nil {
+			if _, err := conn.Do("UNWATCH"); err != nil {
+				return fmt.Errorf("can't unwatch key: %s, err: %v", key, err)
+			}
+			return err
+		}
+
+		if err := conn.Send("MULTI"); err != nil {
+			return fmt.Errorf("can't start transaction, key: %s, err: %v", key, err)
+		}
+		if err := conn.Send("SET", key, data); err != nil {
+			return fmt.Errorf("can't insert encoded elements, key: %s, err: %v", key, err)
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return fmt.Errorf("can't update key: %s, err: %v", key, err)
+		}
+
+		// transaction is aborted when the key has been modified
+		if reply != nil {
+			return nil
+		}
+	}
+}
+
 // Keys returns all redis keys which match the pattern.
 func (s *Service) Keys(pattern string) ([]string, error) {
 	conn := s.rdb.Get()
@@ -207,6 +334,40 @@ func (s *Service) ListPush(key string, data interface{}) error {
 	return nil
 }
 
+// ListTrim trims the list, so only specified number of its last elements is kept.
+func (s *Service) ListTrim(key string, elems int) error {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("LTRIM", key, -elems, -1); err != nil {
+		return fmt.Errorf("can't trim list, key: %s, err: %v", key, err)
+	}
+
+	return nil
+}
+
+// ListPushFront pushes an element to the head of the list.
+func (s *Service) ListPushFront(key string, data interface{}) error {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	reply, err := conn.Do("LPUSH", key, data)
+	if err != nil {
+		return fmt.Errorf("can't push data, key: %s, err: %v", key, err)
+	}
+
+	result, ok := reply.(int64)
+	if !ok {
+		return fmt.Errorf("can't decode redis response, key: %s", key)
+	}
+	if result < 1 {
+		return fmt.Errorf("error! redis should add at least one element, result: %d, key: %s", result, key)
+	}
+
+	log.Printf("Push redis done: %s", key)
+	return nil
+}
+
 // ListPop pops element from the list.
 func (s *Service) ListPop(key string) (interface{}, error) {
 	conn := s.rdb.Get()
@@ -221,6 +382,79 @@ func (s *Service) ListPop(key string) (interface{}, error) {
 	return reply, nil
 }
 
+// ListMove atomically pops the last element of the src list and pushes it to the head of dst list.
+// Returns ErrNotFound when src list is empty.
+func (s *Service) ListMove(src, dst string) ([]byte, error) {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	reply, err := redis.Bytes(conn.Do("RPOPLPUSH", src, dst))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("can't move element from %s to %s, err: %v", src, dst, err)
+	}
+
+	return reply, nil
+}
+
+// ListBlockingMove works like ListMove but waits up to timeout for an element to appear in src list.
+// Returns ErrNotFound when timeout expired.
+func (s *Service) ListBlockingMove(src, dst string, timeout time.Duration) ([]byte, error) {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	reply, err := redis.Bytes(conn.Do("BRPOPLPUSH", src, dst, int(timeout.Seconds())))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("can't move element from %s to %s, err: %v", src, dst, err)
+	}
+
+	return reply, nil
+}
+
+// ListRemove removes first occurrence of the element from the list.
+func (s *Service) ListRemove(key string, data []byte) error {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	n, err := redis.Int(conn.Do("LREM", key, 1, data))
+	if err != nil {
+		return fmt.Errorf("can't remove element, key: %s, err: %v", key, err)
+	}
+	if n < 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ListReplace atomically removes the element from src list and pushes newData to the head of dst list.
+func (s *Service) ListReplace(src string, data []byte, dst string, newData []byte) error {
+	conn := s.rdb.Get()
+	defer conn.Close()
+
+	if err := conn.Send("MULTI"); err != nil {
+		return fmt.Errorf("can't start transaction, key: %s, err: %v", src, err)
+	}
+	if err := conn.Send("LREM", src, 1, data); err != nil {
+		return fmt.Errorf("can't remove element, key: %s, err: %v", src, err)
+	}
+	if err := conn.Send("LPUSH", dst, newData); err != nil {
+		return fmt.Errorf("can't push data, key: %s, err: %v", dst, err)
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return fmt.Errorf("can't move element from %s to %s, err: %v", src, dst, err)
+	}
+
+	return nil
+}
+
 // ListGetLast get last element from the list.
 func (s *Service) ListGetLast(key string) (interface{}, error) {
 	conn := s.rdb.Get()
@@ -239,12 +473,12 @@ func (s *Service) ListGetLast(key string) (interface{}, error) {
 	return reply, nil
 }
 
-// ListGetLast get last element from the list.
+// ListGetLastElements gets specified number of last elements from the list.
 func (s *Service) ListGetLastElements(key string, elems int) ([][]byte, error) {
 	conn := s.rdb.Get()
 	defer conn.Close()
 
-	entries, err := redis.ByteSlices(conn.Do("LRANGE", key, -elems, elems-1))
+	entries, err := redis.ByteSlices(conn.Do("LRANGE", key, -elems, -1))
 	if err != nil {
 		if err == redis.ErrNil {
 			return nil, ErrNotFound
@@ -349,7 +583,6 @@ func (s *Service) SortedSetAdd(key string, value []byte, rank int) (int64, error
 	return reply, nil
 }
 
-
 // SortedSetGetAllRev gets all items from the sorted set in reverse order
 // (from highest to lowest rank).
 func (s *Service) SortedSetGetAllRev(key string) ([][]byte, error) {
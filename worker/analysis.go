@@ -0,0 +1,456 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qfarm/qfarm"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/assign"
+	"golang.org/x/tools/go/analysis/passes/atomic"
+	"golang.org/x/tools/go/analysis/passes/bools"
+	"golang.org/x/tools/go/analysis/passes/composite"
+	"golang.org/x/tools/go/analysis/passes/copylock"
+	"golang.org/x/tools/go/analysis/passes/errorsas"
+	"golang.org/x/tools/go/analysis/passes/httpresponse"
+	"golang.org/x/tools/go/analysis/passes/ifaceassert"
+	"golang.org/x/tools/go/analysis/passes/loopclosure"
+	"golang.org/x/tools/go/analysis/passes/lostcancel"
+	"golang.org/x/tools/go/analysis/passes/nilfunc"
+	"golang.org/x/tools/go/analysis/passes/nilness"
+	"golang.org/x/tools/go/analysis/passes/printf"
+	"golang.org/x/tools/go/analysis/passes/shadow"
+	"golang.org/x/tools/go/analysis/passes/shift"
+	"golang.org/x/tools/go/analysis/passes/stdmethods"
+	"golang.org/x/tools/go/analysis/passes/stringintconv"
+	"golang.org/x/tools/go/analysis/passes/structtag"
+	"golang.org/x/tools/go/analysis/passes/tests"
+	"golang.org/x/tools/go/analysis/passes/unmarshal"
+	"golang.org/x/tools/go/analysis/passes/unreachable"
+	"golang.org/x/tools/go/analysis/passes/unsafeptr"
+	"golang.org/x/tools/go/analysis/passes/unusedresult"
+	"golang.org/x/tools/go/packages"
+)
+
+// Analyzers is the name of the checker which runs analyzers in-process.
+const Analyzers = "analyzers"
+
+var (
+	// analyzers of 'go vet', their issues are errors
+	vetAnalyzers = []*analysis.Analyzer{
+		assign.Analyzer,
+		atomic.Analyzer,
+		bools.Analyzer,
+		composite.Analyzer,
+		copylock.Analyzer,
+		errorsas.Analyzer,
+		httpresponse.Analyzer,
+		ifaceassert.Analyzer,
+		loopclosure.Analyzer,
+		lostcancel.Analyzer,
+		nilfunc.Analyzer,
+		printf.Analyzer,
+		shift.Analyzer,
+		stdmethods.Analyzer,
+		stringintconv.Analyzer,
+		structtag.Analyzer,
+		tests.Analyzer,
+		unmarshal.Analyzer,
+		unreachable.Analyzer,
+		unsafeptr.Analyzer,
+		unusedresult.Analyzer,
+	}
+	// analyzers which report warnings
+	extraAnalyzers = []*analysis.Analyzer{
+		nilness.Analyzer,
+		shadow.Analyzer,
+	}
+
+	analyzers        = map[string]*analysis.Analyzer{}
+	analyzerSeverity = map[string]qfarm.Severity{}
+	defaultAnalyzers []string
+)
+
+func init() {
+	for _, a := range vetAnalyzers {
+		analyzers[a.Name] = a
+		analyzerSeverity[a.Name] = qfarm.Error
+	}
+	for _, a := range extraAnalyzers {
+		analyzers[a.Name] = a
+		analyzerSeverity[a.Name] = qfarm.Warning
+	}
+
+	for name := range analyzers {
+		defaultAnalyzers = append(defaultAnalyzers, name)
+	}
+	sort.Strings(defaultAnalyzers)
+}
+
+// vetAnalyzerNames returns analyzers replacing linters of 'go tool vet', which doesn't exist
+// anymore, keyed by name of the linter.
+func vetAnalyzerNames() map[string][]string {
+	names := map[string][]string{Vetshadow: {"shadow"}}
+	for _, a := range vetAnalyzers {
+		names[Vet] = append(names[Vet], a.Name)
+	}
+
+	return names
+}
+
+// aliasVetLinters replaces linters of 'go tool vet' requested by repo config with analyzers
+// which replaced them.
+func aliasVetLinters(cfg *qfarm.BuildCfg) {
+	aliases := vetAnalyzerNames()
+	linters := make([]string, 0, len(cfg.Linters))
+	analyzers := append([]string(nil), cfg.Analyzers...)
+	requested := make(map[string]bool)
+	for _, a := range analyzers {
+		requested[a] = true
+	}
+
+	for _, name := range cfg.Linters {
+		replaced, ok := aliases[name]
+		if !ok {
+			linters = append(linters, name)
+			continue
+		}
+
+		for _, a := range replaced {
+			if !requested[a] {
+				analyzers = append(analyzers, a)
+				requested[a] = true
+			}
+		}
+	}
+
+	cfg.Linters, cfg.Analyzers = linters, analyzers
+}
+
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedImports |
+	packages.NeedTypes | packages.NeedTypesSizes | packages.NeedSyntax | packages.NeedTypesInfo | packages.NeedModule
+
+// analysisChecker runs go/analysis analyzers in-process. Packages are loaded and type-checked
+// once and shared by all analyzers, dependencies are read from export data.
+type analysisChecker struct {
+	m           *Metalinter
+	cfg         qfarm.BuildCfg
+	analyzers   []*analysis.Analyzer
+	linters     map[*analysis.Analyzer]*qfarm.Linter
+	concurrency chan bool
+	timeout     time.Duration
+}
+
+// newAnalysisChecker creates checker running analyzers requested by the build. Returns nil
+// if none of them exists.
+func (m *Metalinter) newAnalysisChecker(cfg qfarm.BuildCfg, concurrency chan bool, timeout time.Duration) *analysisChecker {
+	c := &analysisChecker{
+		m:           m,
+		cfg:         cfg,
+		linters:     make(map[*analysis.Analyzer]*qfarm.Linter),
+		concurrency: concurrency,
+		timeout:     timeout,
+	}
+
+	for _, name := range cfg.Analyzers {
+		a, ok := analyzers[name]
+		if !ok {
+			warning("Analyzer %s doesn't exist!", name)
+			continue
+		}
+		if _, ok := c.linters[a]; ok {
+			continue
+		}
+
+		c.analyzers = append(c.analyzers, a)
+		c.linters[a] = &qfarm.Linter{Name: a.Name, SeverityOverride: analyzerSeverity[a.Name], EventType: EventTypeAnalyzersDone}
+	}
+
+	if len(c.analyzers) == 0 {
+		return nil
+	}
+
+	return c
+}
+
+func (c *analysisChecker) name() string {
+	return Analyzers
+}
+
+func (c *analysisChecker) eventType() string {
+	return EventTypeAnalyzersDone
+}
+
+func (c *analysisChecker) check(ctx context.Context, paths []string, issues chan<- *qfarm.Issue, errch chan<- error) {
+	c.concurrency <- true
+	defer func() { <-c.concurrency }()
+
+	checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	dirs := make(map[string]bool)
+	for _, p := range paths {
+		dirs[p] = true
+	}
+
+	for dir, patterns := range c.patterns(paths) {
+		start := time.Now()
+		c.m.debug("analyzing with %d analyzers: %v (in %s)", len(c.analyzers), patterns, dir)
+
+		pkgs, err := packages.Load(&packages.Config{
+			Mode:    loadMode,
+			Context: checkCtx,
+			Dir:     dir,
+			Env:     buildEnv(c.cfg),
+			Tests:   c.cfg.IncludeTests,
+		}, patterns...)
+		if err == nil {
+			err = c.analyze(checkCtx, pkgs, dirs, issues)
+		}
+
+		if checkCtx.Err() != nil {
+			if ctx.Err() != nil {
+				errch <- ctx.Err()
+			} else {
+				errch <- &linterTimeoutError{linter: c.name(), path: dir}
+			}
+			return
+		}
+		if err != nil {
+			errch <- fmt.Errorf("can't analyze packages in %s: %v", dir, err)
+			continue
+		}
+
+		c.m.debug("analyzers took %s in %s", time.Now().Sub(start), dir)
+	}
+}
+
+// patterns returns package patterns of the analyzed paths, keyed by directory in which they
+// should be loaded, ie. root of the module containing them or of the repo using GOPATH.
+func (c *analysisChecker) patterns(paths []string) map[string][]string {
+	out := make(map[string][]string)
+	for _, p := range paths {
+		rel, err := filepath.Rel(c.cfg.Path, p)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		dir := c.cfg.Path
+		if len(c.cfg.Modules) > 0 {
+			m, ok := moduleOf(c.cfg.Modules, rel)
+			if !ok {
+				continue
+			}
+			dir = filepath.Join(c.cfg.Path, m.Dir)
+			rel, _ = filepath.Rel(m.Dir, rel)
+		}
+
+		out[dir] = append(out[dir], "./"+filepath.ToSlash(rel))
+	}
+
+	return out
+}
+
+// analyze runs analyzers on loaded packages. Packages are analyzed after packages they import,
+// so facts exported by analyzers (eg. printf wrappers) are visible in importing packages. Only
+// issues found in the analyzed directories are reported.
+func (c *analysisChecker) analyze(ctx context.Context, pkgs []*packages.Package, dirs map[string]bool, issues chan<- *qfarm.Issue) error {
+	roots := make(map[*packages.Package]bool)
+	for _, pkg := range pkgs {
+		// skip generated main packages of test binaries
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+		roots[pkg] = true
+	}
+
+	var order []*packages.Package
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if roots[pkg] {
+			order = append(order, pkg)
+		}
+	})
+
+	// test variants of the package report the same issues
+	reported := make(map[string]bool)
+	facts := make(factsMap)
+	for _, pkg := range order {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		for _, e := range pkg.Errors {
+			c.m.debug("package %s: %v", pkg.PkgPath, e)
+		}
+
+		c.analyzePackage(pkg, facts, func(a *analysis.Analyzer, d analysis.Diagnostic) {
+			posn := pkg.Fset.Position(d.Pos)
+			if !dirs[filepath.Dir(posn.Filename)] {
+				return
+			}
+
+			issue := &qfarm.Issue{
+				Linter:   c.linters[a],
+				Severity: analyzerSeverity[a.Name],
+				Path:     posn.Filename,
+				Line:     posn.Line,
+				Col:      posn.Column,
+				Message:  d.Message,
+			}
+
+			key := a.Name + ":" + issue.String()
+			if reported[key] {
+				return
+			}
+			reported[key] = true
+
+			issues <- issue
+		})
+	}
+
+	return nil
+}
+
+// analyzePackage runs requested analyzers and analyzers they require on the package.
+func (c *analysisChecker) analyzePackage(pkg *packages.Package, facts factsMap, report func(*analysis.Analyzer, analysis.Diagnostic)) {
+	requested := make(map[*analysis.Analyzer]bool)
+	for _, a := range c.analyzers {
+		requested[a] = true
+	}
+
+	results := make(map[*analysis.Analyzer]interface{})
+	done := make(map[*analysis.Analyzer]bool)
+	failed := make(map[*analysis.Analyzer]bool)
+
+	var run func(a *analysis.Analyzer)
+	run = func(a *analysis.Analyzer) {
+		if done[a] {
+			return
+		}
+		done[a] = true
+
+		resultOf := make(map[*analysis.Analyzer]interface{})
+		for _, req := range a.Requires {
+			run(req)
+			if failed[req] {
+				failed[a] = true
+				return
+			}
+			resultOf[req] = results[req]
+		}
+
+		if (len(pkg.Errors) > 0 || len(pkg.TypeErrors) > 0) && !a.RunDespiteErrors {
+			failed[a] = true
+			return
+		}
+
+		pass := &analysis.Pass{
+			Analyzer:     a,
+			Fset:         pkg.Fset,
+			Files:        pkg.Syntax,
+			OtherFiles:   pkg.OtherFiles,
+			IgnoredFiles: pkg.IgnoredFiles,
+			Pkg:          pkg.Types,
+			TypesInfo:    pkg.TypesInfo,
+			TypesSizes:   pkg.TypesSizes,
+			TypeErrors:   pkg.TypeErrors,
+			ResultOf:     resultOf,
+			ReadFile:     ioutil.ReadFile,
+			Report: func(d analysis.Diagnostic) {
+				if requested[a] {
+					report(a, d)
+				}
+			},
+		}
+		if pkg.Module != nil {
+			pass.Module = &analysis.Module{Path: pkg.Module.Path, Version: pkg.Module.Version, GoVersion: pkg.Module.GoVersion}
+		}
+		facts.bind(pass)
+
+		result, err := runAnalyzer(pass)
+		if err != nil {
+			c.m.debug("analyzer %s failed on %s: %v", a.Name, pkg.PkgPath, err)
+			failed[a] = true
+			return
+		}
+		results[a] = result
+	}
+
+	for _, a := range c.analyzers {
+		run(a)
+	}
+}
+
+// runAnalyzer runs the analyzer, its panic is returned as an error.
+func runAnalyzer(pass *analysis.Pass) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return pass.Analyzer.Run(pass)
+}
+
+// factKey identifies fact of the object or package (if obj is nil).
+type factKey struct {
+	obj types.Object
+	pkg *types.Package
+	t   reflect.Type
+}
+
+type factsMap map[factKey]analysis.Fact
+
+// bind sets fact functions of the pass, facts are stored in the map.
+func (f factsMap) bind(pass *analysis.Pass) {
+	get := func(key factKey, fact analysis.Fact) bool {
+		found, ok := f[key]
+		if ok {
+			reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(found).Elem())
+		}
+		return ok
+	}
+
+	pass.ImportObjectFact = func(obj types.Object, fact analysis.Fact) bool {
+		return get(factKey{obj: obj, t: reflect.TypeOf(fact)}, fact)
+	}
+	pass.ImportPackageFact = func(pkg *types.Package, fact analysis.Fact) bool {
+		return get(factKey{pkg: pkg, t: reflect.TypeOf(fact)}, fact)
+	}
+	pass.ExportObjectFact = func(obj types.Object, fact analysis.Fact) {
+		f[factKey{obj: obj, t: reflect.TypeOf(fact)}] = fact
+	}
+	pass.ExportPackageFact = func(fact analysis.Fact) {
+		f[factKey{pkg: pass.Pkg, t: reflect.TypeOf(fact)}] = fact
+	}
+
+	factTypes := make(map[reflect.Type]bool)
+	for _, ft := range pass.Analyzer.FactTypes {
+		factTypes[reflect.TypeOf(ft)] = true
+	}
+	pass.AllObjectFacts = func() []analysis.ObjectFact {
+		var out []analysis.ObjectFact
+		for key, fact := range f {
+			if key.obj != nil && factTypes[key.t] {
+				out = append(out, analysis.ObjectFact{Object: key.obj, Fact: fact})
+			}
+		}
+		return out
+	}
+	pass.AllPackageFacts = func() []analysis.PackageFact {
+		var out []analysis.PackageFact
+		for key, fact := range f {
+			if key.pkg != nil && factTypes[key.t] {
+				out = append(out, analysis.PackageFact{Package: key.pkg, Fact: fact})
+			}
+		}
+		return out
+	}
+}
@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/qfarm/qfarm"
+	"github.com/qfarm/qfarm/redis"
+)
+
+// baseline returns baseline recorded for the repo or nil, if there is none.
+func (w *Worker) baseline(repo string) (*qfarm.Baseline, error) {
+	data, err := w.redis.Get(qfarm.BaselineKey(repo))
+	if err == redis.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var base qfarm.Baseline
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, fmt.Errorf("can't unmarshal baseline of %s: %v", repo, err)
+	}
+
+	return &base, nil
+}
+
+// compareBaseline marks counted issues as new or existing in the baseline. Issues are matched by
+// fingerprints, so they survive unrelated line shifts. Returns issues of the baseline which
+// have been fixed as well. If baseline has been recorded with older version of fingerprints,
+// all issues of the build are fingerprinted with that version for the comparison.
+func compareBaseline(base *qfarm.Baseline, root string, issues, counted []*qfarm.Issue) (*qfarm.BaselineDiff, []*qfarm.Issue, error) {
+	version, err := qfarm.FingerprintsVersion(base.Issues)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't compare issues with baseline of %s: %v", base.Repo, err)
+	}
+	if version != qfarm.FingerprintVersion {
+		current := make([]string, len(issues))
+		for n, i := range issues {
+			current[n] = i.Fingerprint
+		}
+		defer func() {
+			for n, i := range issues {
+				i.Fingerprint = current[n]
+			}
+		}()
+
+		if err := qfarm.SetFingerprintsVersion(issues, root, version); err != nil {
+			return nil, nil, fmt.Errorf("can't compare issues with baseline of %s: %v", base.Repo, err)
+		}
+	}
+
+	introduced, existing, fixed := qfarm.CompareIssues(base.Issues, counted)
+
+	diff := &qfarm.BaselineDiff{BuildNo: base.BuildNo, NewNo: len(introduced), ExistingNo: len(existing), FixedNo: len(fixed)}
+	for _, i := range existing {
+		i.Baseline = qfarm.IssueExisting
+	}
+	for _, i := range introduced {
+		i.Baseline = qfarm.IssueNew
+		switch i.Severity {
+		case qfarm.Error:
+			diff.NewErrorsNo++
+		case qfarm.Warning:
+			diff.NewWarningsNo++
+		}
+	}
+
+	return diff, fixed, nil
+}
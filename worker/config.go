@@ -3,13 +3,14 @@ package worker
 import (
 	"fmt"
 	"github.com/BurntSushi/toml"
+	"github.com/qfarm/qfarm"
+	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"log"
-	"reflect"
-	"github.com/qfarm/qfarm"
 	"os"
-	"gopkg.in/yaml.v2"
 	"path"
+	"reflect"
+	"time"
 )
 
 type Cfg struct {
@@ -42,25 +43,60 @@ type Cfg struct {
 
 	// Minimum token sequence as a clone for dupl - default 50
 	DuplThreshold int
+
+	// QueueVisibilityTimeout - Seconds after which builds of a worker which stopped sending heartbeats are requeued - default 60, min 3
+	QueueVisibilityTimeout int
+
+	// QueueMaxRetries - Number of attempts after which build is moved to dead-letter list - default 3, min 1
+	QueueMaxRetries int
+
+	// LinterTimeout - Seconds after which a single linter run is killed - default 120, min 1
+	LinterTimeout int
+
+	// BuildTimeout - Seconds after which the whole build is aborted - default 1800, min 1
+	BuildTimeout int
+
+	// WorkspaceDir - Directory in which temporary build workspaces are created - default "" (system temp dir)
+	WorkspaceDir string
+
+	// MaxTestRetries - Max number of times failed tests are re-run to detect flaky tests - default 3
+	MaxTestRetries int
+
+	// AllowRepoLinters - Allow repos to define custom linters in .qfarm.yml (they run any command on the worker) - default false
+	AllowRepoLinters bool
+
+	// CustomLinters - Linters which repos might use besides built-in ones - default []
+	CustomLinters []qfarm.CustomLinter
+
+	// Toolchains - GOROOT directories of Go toolchains which might be requested in .qfarm.yml - default [] ('go' from PATH only)
+	Toolchains []string
 }
 
+// minQueueVisibilityTimeout is the shortest visibility timeout (in seconds) of the build queue.
+const minQueueVisibilityTimeout = 3
+
 func NewDefaulConfig() *Cfg {
 	return &Cfg{
-		CheckLastCommitHash:   false,
-		RedisConn:             "127.0.0.1:6379",
-		RedisPass:             "",
-		Debug:                 true,
-		Concurrency:           16,
-		Cyclo:                 10,
-		LLLineLength:          120,
-		GolintMinConfidence:   0.8,
-		GoconstMinOccurrences: 3,
-		DuplThreshold:         50,
+		CheckLastCommitHash:    false,
+		RedisConn:              "127.0.0.1:6379",
+		RedisPass:              "",
+		Debug:                  true,
+		Concurrency:            16,
+		Cyclo:                  10,
+		LLLineLength:           120,
+		GolintMinConfidence:    0.8,
+		GoconstMinOccurrences:  3,
+		DuplThreshold:          50,
+		QueueVisibilityTimeout: 60,
+		QueueMaxRetries:        3,
+		LinterTimeout:          120,
+		BuildTimeout:           1800,
+		MaxTestRetries:         3,
 	}
 }
 
 func Load(path string) (*Cfg, error) {
-	conf := Cfg{}
+	conf := *NewDefaulConfig()
 
 	// load config from file
 	if path == "" {
@@ -76,6 +112,24 @@ func Load(path string) (*Cfg, error) {
 		return nil, err
 	}
 
+	// heartbeats are sent 3 times per visibility timeout
+	if conf.QueueVisibilityTimeout < minQueueVisibilityTimeout {
+		return nil, fmt.Errorf("QueueVisibilityTimeout should be at least %d seconds, got %d", minQueueVisibilityTimeout, conf.QueueVisibilityTimeout)
+	}
+
+	// builds are moved to dead-letter list once attempts reach QueueMaxRetries
+	if conf.QueueMaxRetries < 1 {
+		return nil, fmt.Errorf("QueueMaxRetries should be at least 1, got %d", conf.QueueMaxRetries)
+	}
+
+	// linters and builds are run with timeout context, shorter timeout would kill them at once
+	if conf.LinterTimeout < 1 {
+		return nil, fmt.Errorf("LinterTimeout should be at least 1 second, got %d", conf.LinterTimeout)
+	}
+	if conf.BuildTimeout < 1 {
+		return nil, fmt.Errorf("BuildTimeout should be at least 1 second, got %d", conf.BuildTimeout)
+	}
+
 	conf.Print()
 
 	return &conf, nil
@@ -96,10 +150,34 @@ func (c *Cfg) Print() {
 	}
 }
 
+// Timeouts returns linter and build timeouts for the given build. Repo config might only
+// shorten timeouts set in worker config.
+func (c *Cfg) Timeouts(cfg qfarm.BuildCfg) (linter time.Duration, build time.Duration) {
+	linterSec, buildSec := c.LinterTimeout, c.BuildTimeout
+	if cfg.LinterTimeout > 0 && cfg.LinterTimeout < linterSec {
+		linterSec = cfg.LinterTimeout
+	}
+	if cfg.BuildTimeout > 0 && cfg.BuildTimeout < buildSec {
+		buildSec = cfg.BuildTimeout
+	}
+
+	return time.Duration(linterSec) * time.Second, time.Duration(buildSec) * time.Second
+}
+
+// TestRetries returns number of re-runs of failed tests requested by repo config, limited
+// by worker config.
+func (c *Cfg) TestRetries(cfg qfarm.BuildCfg) int {
+	if cfg.TestRetries > c.MaxTestRetries {
+		return c.MaxTestRetries
+	}
+
+	return cfg.TestRetries
+}
+
 func LoadRepoCfg(repo, repoPath string) (*qfarm.BuildCfg, error) {
 	if _, err := os.Stat(path.Join(repoPath, ".qfarm.yml")); os.IsNotExist(err) {
 		// file config file
-		return &qfarm.BuildCfg{Repo: repo, Path: repoPath, Linters: defaultLinters}, nil
+		return &qfarm.BuildCfg{Repo: repo, Path: repoPath, Linters: defaultLinters, Analyzers: defaultAnalyzers}, nil
 	} else {
 		// file exists
 		yamlFile, err := ioutil.ReadFile(path.Join(repoPath, ".qfarm.yml"))
@@ -118,6 +196,16 @@ func LoadRepoCfg(repo, repoPath string) (*qfarm.BuildCfg, error) {
 			cfg.Linters = defaultLinters
 		}
 
+		if err := validateSeverities(cfg); err != nil {
+			return nil, err
+		}
+
+		// if analyzers list empty - use default list
+		if len(cfg.Analyzers) == 0 {
+			cfg.Analyzers = defaultAnalyzers
+		}
+		aliasVetLinters(&cfg)
+
 		cfg.Repo = repo
 		cfg.Path = repoPath
 
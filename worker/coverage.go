@@ -3,216 +3,214 @@ package worker
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path"
-	"strconv"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/qfarm/qfarm"
+	"github.com/qfarm/qfarm/redis"
 	"log"
 )
 
 type CoverageChecker struct {
 	cfg      *Cfg
+	redis    *redis.Service
 	notifier *Notifier
 }
 
-func NewCoverageChecker(cfg *Cfg, notifier *Notifier) *CoverageChecker {
-	return &CoverageChecker{cfg: cfg, notifier: notifier}
+func NewCoverageChecker(cfg *Cfg, redis *redis.Service, notifier *Notifier) *CoverageChecker {
+	return &CoverageChecker{cfg: cfg, redis: redis, notifier: notifier}
 }
 
-func (c *CoverageChecker) Start(cfg qfarm.BuildCfg, ft *FilesMap) error {
-	report, err := c.RunCoverageAnalysis(cfg)
+// Start runs tests of all packages and applies their coverage to the file tree. If coverage
+// has been uploaded for the analyzed commit, it's used instead of running tests. Data races
+// found by tests are returned in the report, they're stored together with issues of linters.
+func (c *CoverageChecker) Start(ctx context.Context, cfg qfarm.BuildCfg, buildNo int, ft *FilesMap, upload *qfarm.CoverageUpload) (*qfarm.CoverageReport, error) {
+	var report *qfarm.CoverageReport
+	var err error
+	if upload != nil {
+		report, err = c.ImportCoverage(ctx, cfg, buildNo, upload)
+	} else {
+		report, err = c.RunCoverageAnalysis(ctx, cfg, buildNo)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if err != nil {
+		// build goes on without coverage
+		warning("Coverage analysis of repo %s failed: %v", cfg.Repo, err)
 		c.notifier.SendEvent(cfg.Repo, fmt.Sprintf("Coverage error in repo %s", cfg.Repo), EventTypeCoverageErr)
+		return nil, nil
 	}
 
 	c.notifier.SendEvent(cfg.Repo, fmt.Sprintf("Coverage for repo %s done", cfg.Repo), EventTypeCoverageDone)
 
 	if err := ft.ApplyCover(report); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return report, nil
 }
 
-func (c *CoverageChecker) RunCoverageAnalysis(cfg qfarm.BuildCfg) (*qfarm.CoverageReport, error) {
-	packages := make([]qfarm.PackageReport, 0)
-
+func (c *CoverageChecker) RunCoverageAnalysis(ctx context.Context, cfg qfarm.BuildCfg, buildNo int) (*qfarm.CoverageReport, error) {
 	// list all packages
-	out, err := exec.Command("go", "list", path.Join(cfg.Repo, "...")).Output()
+	packages, err := c.listPackages(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	scanner := bufio.NewScanner(bytes.NewReader(out))
-	for scanner.Scan() {
-		packages = append(packages, qfarm.PackageReport{Name: scanner.Text()})
-	}
-
 	// run per package:
 	// go tool cover
 	// go test -cover
-	var rootTotal, rootCovered int64
+	retries := c.cfg.TestRetries(cfg)
+	raceIssues := make(map[string]*qfarm.Issue)
+	mode := coverMode(cfg)
+	merged := newCoverProfile(mode)
 	for i, pac := range packages {
 		c.debug("Starting coverage analysis of pkg: %s", pac.Name)
 		start := time.Now()
-		stamp := fmt.Sprint(start.Nanosecond())
-		cmd := exec.Command("bash", "-c", "go test -v -covermode=set -coverprofile=/tmp/"+stamp+" "+pac.Name)
+		profile := filepath.Join(tempDir(cfg.Workspace), fmt.Sprintf("cover-%d", start.Nanosecond()))
+		args := append([]string{"test"}, testFlags(cfg)...)
+		args = append(args, "-covermode="+mode, "-coverprofile="+profile)
+		if cfg.CoverPkg {
+			args = append(args, "-coverpkg="+coverPkgPattern(cfg, pac))
+		}
+		cmd := goCommand(cfg, append(args, pac.Name)...)
+		cmd.Dir = pac.Dir
 		var stdErr bytes.Buffer
 		cmd.Stderr = &stdErr
 
-		testOut := ""
-		out, err := cmd.Output()
+		out, err := commandOutput(ctx, cmd)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if err != nil {
 			warning("Some tests in package %s failed", pac.Name)
-			testOut = stdErr.String()
-		} else {
-			testOut = string(out)
 		}
 		packages[i].Time = time.Now().Sub(start)
 
-		if strings.Contains(testOut, "[no test files]") {
+		run, err := parseTestEvents(bytes.NewReader(out))
+		if err != nil {
+			return nil, fmt.Errorf("can't parse test output of package %s: %v", pac.Name, err)
+		}
+
+		for _, issue := range parseRaces(run.Output, cfg.Path) {
+			raceIssues[issue.String()] = issue
+		}
+
+		if run.NoTests {
 			c.debug("No tests for package(%s). Continuing", pac.Name)
 			continue
 		}
 
-		// find coverage
-		startI := strings.Index(testOut, "coverage:")
-		endI := strings.Index(testOut, "% of statements")
+		// package might fail without any failed test, eg. when test binary doesn't build
+		_, failedNo, _ := countTests(run.Tests, false)
+		packages[i].Failed = run.Failed && failedNo == 0
 
-		if startI == -1 || endI == -1 {
-			c.debug("Can't parse test output of package(%s). Continuing", pac.Name)
-			continue
-		} else {
-			startI = startI + 10
+		if retries > 0 && failedNo > 0 {
+			if err := c.retryFailedTests(ctx, cfg, pac, run.Tests, retries); err != nil {
+				return nil, err
+			}
 		}
+		packages[i].Tests = run.Tests
 
-		if startI >= endI || endI >= len(testOut) {
-			c.debug("Can't parse test output of package(%s). Indexes are wrong. Continuing", pac.Name)
+		cp, err := os.Open(profile)
+		if err != nil {
+			// package doesn't build or test binary crashed before writing profile
+			c.debug("No coverage profile for package(%s): %s. Continuing", pac.Name, stdErr.String())
 			continue
 		}
 
-		value := testOut[startI:endI]
-		packages[i].Coverage, err = strconv.ParseFloat(value, 64)
+		p, err := parseProfile(cp)
+		cp.Close()
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("can't parse coverage profile of package %s: %v", pac.Name, err)
 		}
+		merged.merge(p)
+	}
+
+	races := make([]*qfarm.Issue, 0, len(raceIssues))
+	for _, issue := range raceIssues {
+		races = append(races, issue)
+	}
+	sort.Sort(qfarm.IssuesByLocation(races))
+
+	return c.report(cfg, buildNo, packages, merged, races)
+}
+
+// ImportCoverage builds coverage report from coverage profile and optional 'go test -json'
+// output which have been produced outside of the worker.
+func (c *CoverageChecker) ImportCoverage(ctx context.Context, cfg qfarm.BuildCfg, buildNo int, upload *qfarm.CoverageUpload) (*qfarm.CoverageReport, error) {
+	packages, err := c.listPackages(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
 
-		cp, err := os.Open("/tmp/" + stamp)
+	profile, err := parseProfile(bytes.NewReader(upload.Profile))
+	if err != nil {
+		return nil, fmt.Errorf("can't parse uploaded coverage profile: %v", err)
+	}
+
+	if len(upload.Tests) > 0 {
+		run, err := parseTestEvents(bytes.NewReader(upload.Tests))
 		if err != nil {
-			return nil, fmt.Errorf("can't open coverage profile file: %v", err)
+			return nil, fmt.Errorf("can't parse uploaded test output: %v", err)
 		}
-		defer cp.Close()
 
-		scanner := bufio.NewScanner(cp)
-		scanner.Scan() // Skip file header.
-		files := make(map[string]qfarm.CoverFileReport)
-		for scanner.Scan() {
-			line := strings.Split(strings.TrimPrefix(scanner.Text(), pac.Name+"/"), ":")
-			var fileName, blocks string
-			if len(line) > 1 {
-				fileName = line[0]
-				blocks = line[1]
-			}
-			block := strings.Split(blocks, " ")
-			var cursors string
-			var numStmt, count int
-			if len(block) > 2 {
-				cursors = block[0]
-				numStmt, err = strconv.Atoi(block[1])
-				if err != nil {
-					return nil, fmt.Errorf("can't parse NumStmt: %v", err)
-				}
-				count, err = strconv.Atoi(block[2])
-				if err != nil {
-					return nil, fmt.Errorf("can't parse Count: %v", err)
-				}
-			}
-			curs := strings.Split(cursors, ",")
-			if len(curs) < 2 {
-				return nil, fmt.Errorf("bad curs len: %+v", curs)
-			}
-			var start, end qfarm.Cursor
-			st := strings.Split(curs[0], ".")
-			if len(st) > 1 {
-				start.Line, err = strconv.Atoi(st[0])
-				if err != nil {
-					return nil, fmt.Errorf("can't parse Line: %v", err)
-				}
-				start.Col, err = strconv.Atoi(st[1])
-				if err != nil {
-					return nil, fmt.Errorf("can't parse Col: %v", err)
-				}
-			}
-			en := strings.Split(curs[1], ".")
-			if len(en) > 1 {
-				end.Line, err = strconv.Atoi(en[0])
-				if err != nil {
-					return nil, fmt.Errorf("can't parse Line: %v", err)
-				}
-				end.Col, err = strconv.Atoi(en[1])
-				if err != nil {
-					return nil, fmt.Errorf("can't parse Col: %v", err)
-				}
-			}
-			coverBlock := qfarm.CoverBlock{Start: start, End: end, NumStmt: int64(numStmt), Count: int64(count)}
-
-			file := files[fileName]
-			file.Blocks = append(file.Blocks, coverBlock)
-			files[fileName] = file
-		}
-		if err := scanner.Err(); err != nil {
-			return nil, fmt.Errorf("scanner error while reading coverage profile file: %v", err)
-		}
-
-		var pacTotal, pacCovered int64
-		for f, report := range files {
-			var total, covered int64
-			for _, b := range report.Blocks {
-				total += b.NumStmt
-				pacTotal += b.NumStmt
-				rootTotal += b.NumStmt
-				if b.Count > 0 {
-					covered += b.NumStmt
-					pacCovered += b.NumStmt
-					rootCovered += b.NumStmt
-				}
-			}
-			if total == 0 {
-				report.Coverage = 0
-				files[f] = report
-				continue
-			}
-			report.Coverage = float64(covered) / float64(total) * 100
-			files[f] = report
+		tests := make(map[string][]qfarm.TestResult)
+		for _, t := range run.Tests {
+			tests[t.Package] = append(tests[t.Package], t)
 		}
-
-		if pacTotal > 0 {
-			packages[i].Coverage = float64(pacCovered) / float64(pacTotal) * 100
+		for i := range packages {
+			packages[i].Tests = tests[packages[i].Name]
 		}
+	}
 
-		// find no of failed and passed tests
-		packages[i].PassedNo = strings.Count(testOut, "--- PASS")
-		packages[i].FailedNo = strings.Count(testOut, "--- FAIL")
-		packages[i].TestsNo = packages[i].PassedNo + packages[i].FailedNo
-		packages[i].Files = files
+	return c.report(cfg, buildNo, packages, profile, nil)
+}
+
+// report summarizes coverage and test results of all packages.
+func (c *CoverageChecker) report(cfg qfarm.BuildCfg, buildNo int, packages []qfarm.PackageReport, merged *coverProfile, races []*qfarm.Issue) (*qfarm.CoverageReport, error) {
+	// with -coverpkg profile of every package holds blocks of other packages as well
+	var rootTotal, rootCovered int64
+	for i, pac := range packages {
+		packages[i].Files = merged.packageFiles(pac.Name)
+		for _, f := range packages[i].Files {
+			total, covered := countStmts(f.Blocks)
+			packages[i].NumStmt += total
+			packages[i].Covered += covered
+		}
 
-		if packages[i].FailedNo > 0 {
-			packages[i].Failed = true
+		if packages[i].NumStmt > 0 {
+			packages[i].Coverage = float64(packages[i].Covered) / float64(packages[i].NumStmt) * 100
 		}
+		rootTotal += packages[i].NumStmt
+		rootCovered += packages[i].Covered
 
 		c.debug("Coverage anlysis of package(%s): %f", pac.Name, packages[i].Coverage)
 	}
 
-	report := qfarm.CoverageReport{Repo: cfg.Repo, Packages: packages}
+	report := qfarm.CoverageReport{Repo: cfg.Repo, Packages: packages, Races: races}
+
+	if err := c.markFlakyByHistory(cfg, buildNo, &report); err != nil {
+		return nil, fmt.Errorf("can't update test history: %v", err)
+	}
+
+	// find no of failed and passed tests
+	for i := range report.Packages {
+		pkg := &report.Packages[i]
+		pkg.PassedNo, pkg.FailedNo, pkg.FlakyNo = countTests(pkg.Tests, cfg.IgnoreFlaky)
+		pkg.TestsNo = pkg.PassedNo + pkg.FailedNo
+		pkg.Failed = pkg.Failed || pkg.FailedNo > 0
 
-	for _, pkg := range report.Packages {
 		report.TotalFailedNo += pkg.FailedNo
 		report.TotalPassedNo += pkg.PassedNo
+		report.TotalFlakyNo += pkg.FlakyNo
 		report.TotalTestsNo += pkg.TestsNo
 		report.TotalTime += pkg.Time
 	}
@@ -229,6 +227,64 @@ func (c *CoverageChecker) RunCoverageAnalysis(cfg qfarm.BuildCfg) (*qfarm.Covera
 	return &report, nil
 }
 
+// coverMode returns coverage mode requested by repo config. Race detector requires atomic counters.
+func coverMode(cfg qfarm.BuildCfg) string {
+	switch {
+	case cfg.Race:
+		return coverModeAtomic
+	case cfg.CoverMode == coverModeCount || cfg.CoverMode == coverModeAtomic:
+		return cfg.CoverMode
+	default:
+		return coverModeSet
+	}
+}
+
+// coverPkgPattern returns -coverpkg pattern matching all packages of the module (or the repo
+// for GOPATH based repos) of the package.
+func coverPkgPattern(cfg qfarm.BuildCfg, pac qfarm.PackageReport) string {
+	if pac.Module == "" {
+		return path.Join(cfg.Repo, "...")
+	}
+
+	return path.Join(pac.Module, "...")
+}
+
+// listPackages lists packages of the repo. Packages of modules are listed from module root,
+// so module path might differ from repo path.
+func (c *CoverageChecker) listPackages(ctx context.Context, cfg qfarm.BuildCfg) ([]qfarm.PackageReport, error) {
+	modules := cfg.Modules
+	if len(modules) == 0 {
+		// GOPATH based repo
+		modules = []qfarm.Module{{Dir: "."}}
+	}
+
+	packages := make([]qfarm.PackageReport, 0)
+	for _, m := range modules {
+		pattern := "./..."
+		if m.Path == "" {
+			pattern = path.Join(cfg.Repo, "...")
+		}
+
+		cmd := goCommand(cfg, "list", "-f", "{{.ImportPath}}\t{{.Dir}}", pattern)
+		cmd.Dir = filepath.Join(cfg.Path, m.Dir)
+		out, err := commandOutput(ctx, cmd)
+		if err != nil {
+			return nil, fmt.Errorf("can't list packages of module %s: %v", m.Path, err)
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		for scanner.Scan() {
+			parts := strings.SplitN(scanner.Text(), "\t", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			packages = append(packages, qfarm.PackageReport{Name: parts[0], Dir: parts[1], Module: m.Path})
+		}
+	}
+
+	return packages, nil
+}
+
 func (m *CoverageChecker) debug(format string, args ...interface{}) {
 	if m.cfg.Debug {
 		log.Printf("DEBUG: "+format, args...)
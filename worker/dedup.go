@@ -0,0 +1,200 @@
+package worker
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+)
+
+// linters which report the same kind of problems, their issues at the same line are duplicates
+// if they concern the same identifier
+var issueCategories = map[string]string{
+	Deadcode:    "unused",
+	Varcheck:    "unused",
+	Structcheck: "unused",
+	Vetshadow:   "shadow",
+	"shadow":    "shadow",
+}
+
+var (
+	quotedIdent = regexp.MustCompile("[\"'`]([\\w.]+)[\"'`]")
+	unusedIdent = regexp.MustCompile(`([\w.]+) is unused`)
+)
+
+// checks of 'go tool vet' recognized by their messages, keyed by name of the analyzer which
+// reports the same problems
+var vetChecks = []struct {
+	analyzer string
+	message  *regexp.Regexp
+}{
+	{"printf", regexp.MustCompile(`(?i)printf|println|print call|formatting directive|format %|verb`)},
+	{"copylocks", regexp.MustCompile(`(?:copies|passes|returns) lock|lock by value|copylocks`)},
+	{"unreachable", regexp.MustCompile(`unreachable code`)},
+	{"lostcancel", regexp.MustCompile(`cancel function`)},
+	{"structtag", regexp.MustCompile(`struct field tag`)},
+	{"unusedresult", regexp.MustCompile(`result of .* call not used`)},
+	{"assign", regexp.MustCompile(`self-assignment`)},
+	{"bools", regexp.MustCompile(`(?:redundant|suspect) (?:or|and)`)},
+	{"composites", regexp.MustCompile(`composite literal uses unkeyed fields`)},
+	{"nilfunc", regexp.MustCompile(`comparison of function .* is always`)},
+	{"shift", regexp.MustCompile(`too small for shift|shift of .* equals`)},
+	{"unsafeptr", regexp.MustCompile(`misuse of unsafe\.Pointer`)},
+	{"atomic", regexp.MustCompile(`direct assignment to atomic value`)},
+	{"stdmethods", regexp.MustCompile(`should have signature`)},
+	{"httpresponse", regexp.MustCompile(`using \w+ before checking for errors`)},
+	{"tests", regexp.MustCompile(`malformed (?:name|example)|should be niladic|refers to unknown`)},
+	{"loopclosure", regexp.MustCompile(`captured by func literal`)},
+}
+
+var (
+	// position and 'vet:' prefix printed by some vet versions before the message
+	vetPrefix = regexp.MustCompile(`^(?:vet: )?(?:[^\s:]+\.go:\d+(?::\d+)?: )?(?:\d+: )?`)
+
+	// type errors of older go/types versions, eg. "undeclared name: x"
+	typeErrorSynonyms = strings.NewReplacer("undeclared name: ", "undefined: ", "declared but not used", "declared and not used")
+)
+
+// dedupIssues merges issues reported at the same line by different linters which have the same
+// meaning. Merged issue has the highest severity of the issues and keeps names of all linters
+// which reported it.
+func dedupIssues(issues []*qfarm.Issue) []*qfarm.Issue {
+	groups := make(map[string][]*qfarm.Issue)
+	order := make([]string, 0)
+	for _, i := range issues {
+		key := fmt.Sprintf("%s:%d:%s", i.Path, i.Line, issueMeaning(i))
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	out := make([]*qfarm.Issue, 0, len(order))
+	for _, key := range order {
+		group := groups[key]
+		if len(group) == 1 {
+			out = append(out, group[0])
+			continue
+		}
+
+		sort.Sort(byPrecedence(group))
+		merged := group[0]
+		linters := make(map[string]bool)
+		for _, i := range group {
+			linters[i.Linter.String()] = true
+			for _, l := range i.Linters {
+				linters[l] = true
+			}
+		}
+
+		merged.Linters = make([]string, 0, len(linters))
+		for l := range linters {
+			merged.Linters = append(merged.Linters, l)
+		}
+		sort.Strings(merged.Linters)
+
+		out = append(out, merged)
+	}
+
+	return out
+}
+
+// reportedBy checks whether the issue was reported by the linter, alone or together with
+// other linters.
+func reportedBy(i *qfarm.Issue, linter string) bool {
+	if i.Linter != nil && i.Linter.Name == linter {
+		return true
+	}
+	for _, l := range i.Linters {
+		if l == linter {
+			return true
+		}
+	}
+
+	return false
+}
+
+// issueMeaning returns what the issue is about: category of the linter and identifier
+// of the issue, or normalized message of linters without category.
+func issueMeaning(i *qfarm.Issue) string {
+	linter := i.Linter.String()
+	switch {
+	case linter == Vet:
+		return vetMeaning(i.Message)
+	case linter == Gotype:
+		return "typecheck:" + typeError(i.Message)
+	case isVetAnalyzer(linter):
+		// analyzers of 'go vet' report the same problems as checks of 'go tool vet'
+		return "vet:" + linter
+	}
+
+	category, ok := issueCategories[linter]
+	if !ok {
+		return strings.ToLower(strings.Join(strings.Fields(i.Message), " "))
+	}
+
+	subject := ""
+	if m := quotedIdent.FindStringSubmatch(i.Message); m != nil {
+		subject = m[1]
+	} else if m := unusedIdent.FindStringSubmatch(i.Message); m != nil {
+		subject = m[1]
+	} else if fields := strings.Fields(i.Message); len(fields) > 0 {
+		subject = fields[len(fields)-1]
+	}
+
+	// structcheck reports fields with type name, eg. T.field
+	if n := strings.LastIndex(subject, "."); n >= 0 {
+		subject = subject[n+1:]
+	}
+
+	return category + ":" + subject
+}
+
+// vetMeaning returns meaning of issue reported by 'go tool vet'. Messages of its checks differ
+// from messages of analyzers which replaced them, so they're matched by the check. Type errors
+// printed by vet are matched with issues of gotype.
+func vetMeaning(msg string) string {
+	msg = vetPrefix.ReplaceAllString(strings.TrimSpace(msg), "")
+	for _, c := range vetChecks {
+		if c.message.MatchString(msg) {
+			return "vet:" + c.analyzer
+		}
+	}
+
+	return "typecheck:" + typeError(msg)
+}
+
+// typeError normalizes message of the type error.
+func typeError(msg string) string {
+	msg = vetPrefix.ReplaceAllString(strings.TrimSpace(msg), "")
+	return strings.ToLower(strings.Join(strings.Fields(typeErrorSynonyms.Replace(msg)), " "))
+}
+
+// isVetAnalyzer checks whether linter is an in-process analyzer of 'go vet'.
+func isVetAnalyzer(linter string) bool {
+	for _, a := range vetAnalyzers {
+		if a.Name == linter {
+			return true
+		}
+	}
+
+	return false
+}
+
+// byPrecedence sorts issues so the one which should be kept is first: with the highest
+// severity, then with column.
+type byPrecedence []*qfarm.Issue
+
+func (i byPrecedence) Len() int      { return len(i) }
+func (i byPrecedence) Swap(a, b int) { i[a], i[b] = i[b], i[a] }
+func (i byPrecedence) Less(a, b int) bool {
+	if i[a].Severity.Rank() != i[b].Severity.Rank() {
+		return i[a].Severity.Rank() > i[b].Severity.Rank()
+	}
+	if (i[a].Col != 0) != (i[b].Col != 0) {
+		return i[a].Col != 0
+	}
+	return i[a].Linter.String() < i[b].Linter.String()
+}
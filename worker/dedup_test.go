@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/qfarm/qfarm"
+)
+
+func TestDedupIssues(t *testing.T) {
+	tests := []struct {
+		name   string
+		issues []*qfarm.Issue
+
+		// linter, severity and linters of the issues left
+		want []qfarm.Issue
+	}{
+		{
+			name: "unused",
+			issues: []*qfarm.Issue{
+				newIssue(Deadcode, qfarm.Warning, 3, "x is unused"),
+				newIssue(Varcheck, qfarm.Error, 3, "`x` is unused"),
+			},
+			want: []qfarm.Issue{
+				{Linter: &qfarm.Linter{Name: Varcheck}, Severity: qfarm.Error, Linters: []string{Deadcode, Varcheck}},
+			},
+		},
+		{
+			name: "shadow",
+			issues: []*qfarm.Issue{
+				newIssue(Vetshadow, qfarm.Warning, 5, `declaration of "err" shadows declaration at p.go:3`),
+				newIssue("shadow", qfarm.Warning, 5, `declaration of "err" shadows declaration at line 3`),
+			},
+			want: []qfarm.Issue{
+				{Linter: &qfarm.Linter{Name: "shadow"}, Severity: qfarm.Warning, Linters: []string{"shadow", Vetshadow}},
+			},
+		},
+		{
+			name: "vet check and analyzer",
+			issues: []*qfarm.Issue{
+				newIssue(Vet, qfarm.Warning, 7, "p.go:7: arg x for printf verb %d of wrong type: string"),
+				newIssue("printf", qfarm.Error, 7, "fmt.Printf format %d has arg x of wrong type string"),
+				newIssue("copylocks", qfarm.Error, 7, "assignment copies lock value to y: sync.Mutex"),
+			},
+			want: []qfarm.Issue{
+				{Linter: &qfarm.Linter{Name: "printf"}, Severity: qfarm.Error, Linters: []string{"printf", Vet}},
+				{Linter: &qfarm.Linter{Name: "copylocks"}, Severity: qfarm.Error},
+			},
+		},
+		{
+			name: "type errors",
+			issues: []*qfarm.Issue{
+				newIssue(Gotype, qfarm.Error, 9, "undeclared name: y"),
+				newIssue(Vet, qfarm.Error, 9, "vet: p.go:9:2: undefined: y"),
+			},
+			want: []qfarm.Issue{
+				{Linter: &qfarm.Linter{Name: Gotype}, Severity: qfarm.Error, Linters: []string{Gotype, Vet}},
+			},
+		},
+		{
+			name: "not duplicates",
+			issues: []*qfarm.Issue{
+				newIssue(Deadcode, qfarm.Warning, 3, "x is unused"),
+				newIssue(Varcheck, qfarm.Warning, 4, "x is unused"),
+				newIssue(Varcheck, qfarm.Warning, 4, "y is unused"),
+				newIssue("golint", qfarm.Info, 4, "exported var Y should have comment"),
+			},
+			want: []qfarm.Issue{
+				{Linter: &qfarm.Linter{Name: Deadcode}, Severity: qfarm.Warning},
+				{Linter: &qfarm.Linter{Name: Varcheck}, Severity: qfarm.Warning},
+				{Linter: &qfarm.Linter{Name: Varcheck}, Severity: qfarm.Warning},
+				{Linter: &qfarm.Linter{Name: "golint"}, Severity: qfarm.Info},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got := dedupIssues(tt.issues)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %d issues, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+		for i, want := range tt.want {
+			g := got[i]
+			if g.Linter.String() != want.Linter.String() || g.Severity != want.Severity || !equalStrings(g.Linters, want.Linters) {
+				t.Errorf("%s: issue %d: got %s %s %v, want %s %s %v", tt.name, i,
+					g.Linter, g.Severity, g.Linters, want.Linter, want.Severity, want.Linters)
+			}
+		}
+	}
+}
+
+func newIssue(linter string, severity qfarm.Severity, line int, msg string) *qfarm.Issue {
+	return &qfarm.Issue{Linter: &qfarm.Linter{Name: linter}, Severity: severity, Path: "/repo/p.go", Line: line, Message: msg}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestSetSeveritiesOfMergedIssues(t *testing.T) {
+	cfg := qfarm.BuildCfg{
+		Path:          "/repo",
+		Severities:    map[string]qfarm.Severity{Deadcode: qfarm.Info, Varcheck: qfarm.Error, "golint": qfarm.Warning},
+		SeverityRules: []qfarm.SeverityRule{{Linters: []string{Vetshadow}, Severity: qfarm.Info}},
+	}
+
+	issues := dedupIssues([]*qfarm.Issue{
+		newIssue(Deadcode, qfarm.Warning, 3, "x is unused"),
+		newIssue(Varcheck, qfarm.Warning, 3, "x is unused"),
+		newIssue(Vetshadow, qfarm.Error, 5, `declaration of "err" shadows declaration at p.go:3`),
+		newIssue("shadow", qfarm.Error, 5, `declaration of "err" shadows declaration at line 3`),
+		newIssue("golint", qfarm.Info, 7, "exported var Y should have comment"),
+	})
+	setSeverities(cfg, issues)
+
+	want := []qfarm.Severity{qfarm.Error, qfarm.Info, qfarm.Warning}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d", len(issues), len(want))
+	}
+	for i, s := range want {
+		if issues[i].Severity != s {
+			t.Errorf("issue %d of %v: got severity %s, want %s", i, issues[i].Linters, issues[i].Severity, s)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// runCommand runs the command in its own process group and waits for it to finish.
+// When ctx is done before the command exits, the whole process group is killed,
+// so no children (eg. test binaries spawned by 'go test') are left behind.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// negative pid kills the whole process group
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// commandOutput runs the command like runCommand and returns its standard output.
+func commandOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	err := runCommand(ctx, cmd)
+	return stdout.Bytes(), err
+}
@@ -98,6 +98,9 @@ func (t *FilesMap) ApplyIssue(i *qfarm.Issue) error {
 			if i.Severity == qfarm.Warning {
 				val.WarningsNo++
 			}
+			if i.Severity == qfarm.Info {
+				val.InfosNo++
+			}
 			t.FilesMap[key] = val
 		} else {
 			log.Printf("WARNING: Can't find %s key in FilesMap", key)
@@ -107,11 +110,35 @@ func (t *FilesMap) ApplyIssue(i *qfarm.Issue) error {
 	return nil
 }
 
+// codeContext returns func which returns code around the issue: its line and adjacent lines
+// with trimmed indentation, so the context doesn't change when the code is only re-indented.
+func (t *FilesMap) codeContext() func(i *qfarm.Issue) string {
+	files := make(map[string][]string)
+	return func(i *qfarm.Issue) string {
+		lines, ok := files[i.Path]
+		if !ok {
+			if node, exists := t.FilesMap[i.Path]; exists && !node.Dir {
+				lines = strings.Split(string(node.Content), "\n")
+			}
+			files[i.Path] = lines
+		}
+
+		context := make([]string, 0, 3)
+		for n := i.Line - 1; n <= i.Line+1; n++ {
+			if n >= 1 && n <= len(lines) {
+				context = append(context, strings.TrimSpace(lines[n-1]))
+			}
+		}
+
+		return strings.Join(context, "\n")
+	}
+}
+
 func (t *FilesMap) ApplyCover(r *qfarm.CoverageReport) error {
 	for k, fm := range t.FilesMap {
 	packages:
 		for _, p := range r.Packages {
-			if fm.Dir && strings.HasSuffix(k, p.Name) {
+			if fm.Dir && packageDirMatches(k, p) {
 				t.FilesMap[k].Coverage = p.Coverage
 				t.FilesMap[k].TestsNo = p.TestsNo
 				t.FilesMap[k].FailedNo = p.FailedNo
@@ -119,8 +146,7 @@ func (t *FilesMap) ApplyCover(r *qfarm.CoverageReport) error {
 				break
 			}
 			for f, v := range p.Files {
-				path := filepath.Join(p.Name, f)
-				if strings.HasSuffix(k, path) {
+				if packageFileMatches(k, p, f) {
 					t.FilesMap[k].Coverage = v.Coverage
 					t.FilesMap[k].Blocks = v.Blocks[:]
 					break packages
@@ -134,3 +160,22 @@ func (t *FilesMap) ApplyCover(r *qfarm.CoverageReport) error {
 	t.FilesMap[t.Root].PassedNo = r.TotalPassedNo
 	return nil
 }
+
+// packageDirMatches checks whether path points at the package directory. Import path of packages
+// inside Go modules doesn't have to match their location, so directory reported by go list is used.
+func packageDirMatches(path string, p qfarm.PackageReport) bool {
+	if p.Dir != "" {
+		return path == p.Dir
+	}
+
+	return strings.HasSuffix(path, p.Name)
+}
+
+// packageFileMatches checks whether path points at the file of the package.
+func packageFileMatches(path string, p qfarm.PackageReport, file string) bool {
+	if p.Dir != "" {
+		return path == filepath.Join(p.Dir, file)
+	}
+
+	return strings.HasSuffix(path, filepath.Join(p.Name, file))
+}
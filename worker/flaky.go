@@ -0,0 +1,199 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+	"github.com/qfarm/qfarm/redis"
+)
+
+// Number of builds kept in history of every test.
+const testHistorySize = 10
+
+// Test outcomes recorded in test history.
+const (
+	historyPassed = 'p'
+	historyFailed = 'f'
+	historyFlaky  = 'x'
+)
+
+// retryFailedTests re-runs every failed test of the package up to retries times. Test which
+// passes in any of the re-runs is marked as flaky.
+func (c *CoverageChecker) retryFailedTests(ctx context.Context, cfg qfarm.BuildCfg, pkg qfarm.PackageReport, tests []qfarm.TestResult, retries int) error {
+	parents := parentTests(tests)
+	for i, t := range tests {
+		if t.Status != qfarm.TestFailed || parents[t.Package+"."+t.Name] {
+			continue
+		}
+
+		tests[i].Attempts = 1
+		for a := 0; a < retries; a++ {
+			status, err := c.runTest(ctx, cfg, pkg, t.Name)
+			if err != nil {
+				return err
+			}
+
+			tests[i].Attempts++
+			if status == qfarm.TestPassed {
+				c.debug("Test %s of package %s passed on attempt %d, marking as flaky", t.Name, pkg.Name, tests[i].Attempts)
+				tests[i].Flaky = true
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// runTest runs single test of the package and returns its outcome.
+func (c *CoverageChecker) runTest(ctx context.Context, cfg qfarm.BuildCfg, pkg qfarm.PackageReport, name string) (qfarm.TestStatus, error) {
+	cfg.Count = 1
+	args := append([]string{"test"}, testFlags(cfg)...)
+	cmd := goCommand(cfg, append(args, "-run", runPattern(name), pkg.Name)...)
+	cmd.Dir = pkg.Dir
+
+	// failed test makes 'go test' exit with an error, outcome is read from its output
+	out, _ := commandOutput(ctx, cmd)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	run, err := parseTestEvents(bytes.NewReader(out))
+	if err != nil {
+		return "", err
+	}
+
+	for _, t := range run.Tests {
+		if t.Name == name {
+			return t.Status, nil
+		}
+	}
+
+	return qfarm.TestFailed, nil
+}
+
+// runPattern returns -run pattern which matches only the test with given name, eg. TestA/sub_1
+// gives ^TestA$/^sub_1$.
+func runPattern(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(p) + "$"
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// markFlakyByHistory records outcomes of all tests of the build in test history of the ref
+// and marks tests whose outcome keeps changing between builds as flaky. Outcomes of the build
+// retried from the queue have been recorded already, so they aren't recorded again.
+func (c *CoverageChecker) markFlakyByHistory(cfg qfarm.BuildCfg, buildNo int, report *qfarm.CoverageReport) error {
+	key := testHistoryKey(cfg.Repo, cfg.Ref)
+	recorded, err := c.lastHistoryBuild(key)
+	if err != nil {
+		return err
+	}
+	record := buildNo > recorded
+
+	for i := range report.Packages {
+		tests := report.Packages[i].Tests
+		parents := parentTests(tests)
+		for j, t := range tests {
+			field := t.Package + "." + t.Name
+			if parents[field] || t.Status == qfarm.TestSkipped {
+				continue
+			}
+
+			history, err := c.redis.HashGet(key, field)
+			if err != nil && err != redis.ErrNotFound {
+				return err
+			}
+
+			outcome := byte(historyPassed)
+			switch {
+			case t.Flaky:
+				outcome = historyFlaky
+			case t.Status == qfarm.TestFailed:
+				outcome = historyFailed
+			}
+
+			if record {
+				history = append(history, outcome)
+				if len(history) > testHistorySize {
+					history = history[len(history)-testHistorySize:]
+				}
+
+				if err := c.redis.HashSet(key, field, history); err != nil {
+					return err
+				}
+			}
+
+			if flakyHistory(history) {
+				tests[j].Flaky = true
+			}
+		}
+	}
+
+	if !record {
+		return nil
+	}
+	return c.redis.Set(key+":build", -1, buildNo)
+}
+
+// testHistoryKey returns key of the hash with test history of the ref. Tests might fail on
+// a branch and pass on others, so each ref keeps its own history.
+func testHistoryKey(repo, ref string) string {
+	return "tests-history:" + refBuildsKey(repo, ref)
+}
+
+// lastHistoryBuild returns number of the last build recorded in the test history.
+func (c *CoverageChecker) lastHistoryBuild(key string) (int, error) {
+	data, err := c.redis.Get(key + ":build")
+	if err == redis.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(string(data))
+}
+
+// flakyHistory checks whether test was flaky in any of the recorded builds or its outcome
+// changed at least twice (eg. pass, fail, pass). Single change means that test got broken or fixed.
+func flakyHistory(history []byte) bool {
+	if bytes.IndexByte(history, historyFlaky) >= 0 {
+		return true
+	}
+
+	changes := 0
+	for i := 1; i < len(history); i++ {
+		if history[i] != history[i-1] {
+			changes++
+		}
+	}
+
+	return changes >= 2
+}
+
+// flakyTests returns flaky tests of the build without their output.
+func flakyTests(report *qfarm.CoverageReport) []qfarm.TestResult {
+	if report == nil {
+		return nil
+	}
+
+	var flaky []qfarm.TestResult
+	for _, pkg := range report.Packages {
+		for _, t := range pkg.Tests {
+			if t.Flaky {
+				t.Output = ""
+				flaky = append(flaky, t)
+			}
+		}
+	}
+
+	return flaky
+}
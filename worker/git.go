@@ -0,0 +1,137 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+)
+
+var commitRef = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// checkoutRef checks out the ref (branch, tag, commit or pull request) in the repo.
+func checkoutRef(ctx context.Context, repoPath, ref string) error {
+	ref = qfarm.NormalizeRef(ref)
+
+	if commitRef.MatchString(ref) {
+		// clone contains whole history, so commit might be already there
+		if err := git(ctx, repoPath, "checkout", "--quiet", "--detach", ref); err == nil {
+			return nil
+		}
+	}
+
+	refspec := ref
+	if strings.HasPrefix(ref, "pull/") {
+		refspec = "refs/" + ref + "/head"
+	}
+
+	if err := git(ctx, repoPath, "fetch", "--quiet", "origin", refspec); err != nil {
+		return fmt.Errorf("can't fetch ref %s: %v", ref, err)
+	}
+
+	if err := git(ctx, repoPath, "checkout", "--quiet", "--detach", "FETCH_HEAD"); err != nil {
+		return fmt.Errorf("can't checkout ref %s: %v", ref, err)
+	}
+
+	return nil
+}
+
+// currentBranch returns name of the branch checked out in the repo.
+func currentBranch(ctx context.Context, repoPath string) (string, error) {
+	return gitOutput(ctx, repoPath, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// defaultBranch returns name of the default branch of the origin.
+func defaultBranch(ctx context.Context, repoPath string) (string, error) {
+	ref, err := gitOutput(ctx, repoPath, "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimPrefix(ref, "origin/"), nil
+}
+
+// mergeBase returns the best common ancestor of HEAD and the branch of the origin.
+func mergeBase(ctx context.Context, repoPath, branch string) (string, error) {
+	return gitOutput(ctx, repoPath, "merge-base", "HEAD", "origin/"+branch)
+}
+
+// changedLines returns lines added or modified since the base commit, keyed by path of Go file
+// relative to the repo root.
+func changedLines(ctx context.Context, repoPath, base string) (map[string][]int, error) {
+	cmd := exec.Command("git", "diff", "--unified=0", "--no-color", base, "HEAD", "--", "*.go")
+	cmd.Dir = repoPath
+
+	out, err := commandOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("can't diff %s: %v", base, err)
+	}
+
+	return parseDiff(string(out)), nil
+}
+
+// @@ -12,3 +12,4 @@ func main() {
+var diffHunk = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// parseDiff parses unified diff with no context lines.
+func parseDiff(diff string) map[string][]int {
+	lines := make(map[string][]int)
+	file := ""
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if file == "/dev/null" {
+				file = ""
+			}
+		case strings.HasPrefix(line, "@@") && file != "":
+			m := diffHunk.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+
+			start, _ := strconv.Atoi(m[1])
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			for i := 0; i < count; i++ {
+				lines[file] = append(lines[file], start+i)
+			}
+		}
+	}
+
+	return lines
+}
+
+// gitOutput runs git command and returns its trimmed output.
+func gitOutput(ctx context.Context, repoPath string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+
+	out, err := commandOutput(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func git(ctx context.Context, repoPath string, args ...string) error {
+	var out bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := runCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("git %s: %v %s", args[0], err, strings.TrimSpace(out.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/qfarm/qfarm"
+)
+
+// testEvent is a single event printed by 'go test -json' (see 'go doc test2json').
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// testRun holds results of 'go test -json' run of the single package.
+type testRun struct {
+	Tests   []qfarm.TestResult
+	Failed  bool
+	NoTests bool
+
+	// whole output of the run
+	Output string
+}
+
+// parseTestEvents reads 'go test -json' output. Lines which aren't test events (eg. build
+// errors printed by older toolchains) are ignored.
+func parseTestEvents(r io.Reader) (*testRun, error) {
+	run := &testRun{}
+	tests := make(map[string]*qfarm.TestResult)
+	order := make([]string, 0)
+
+	var output bytes.Buffer
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+
+		var e testEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			continue
+		}
+		output.WriteString(e.Output)
+
+		if e.Test == "" {
+			// package level event
+			switch e.Action {
+			case "fail":
+				run.Failed = true
+			case "output":
+				if strings.Contains(e.Output, "[no test files]") {
+					run.NoTests = true
+				}
+			}
+			continue
+		}
+
+		key := e.Package + "." + e.Test
+		t, ok := tests[key]
+		if !ok {
+			t = &qfarm.TestResult{Package: e.Package, Name: e.Test}
+			if i := strings.LastIndex(e.Test, "/"); i > 0 {
+				t.Parent = e.Test[:i]
+			}
+			tests[key] = t
+			order = append(order, key)
+		}
+
+		// with -count=N test is run N times, single failure fails the test
+		switch e.Action {
+		case "output":
+			t.Output += e.Output
+		case "pass":
+			if t.Status != qfarm.TestFailed {
+				t.Status = qfarm.TestPassed
+			}
+		case "fail":
+			t.Status = qfarm.TestFailed
+		case "skip":
+			if t.Status == "" {
+				t.Status = qfarm.TestSkipped
+			}
+		}
+		if e.Elapsed > 0 {
+			t.Time += time.Duration(e.Elapsed * float64(time.Second))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	run.Output = output.String()
+	for _, key := range order {
+		t := tests[key]
+		if t.Status == "" {
+			// test binary crashed or has been killed
+			t.Status = qfarm.TestFailed
+			run.Failed = true
+		}
+		run.Tests = append(run.Tests, *t)
+	}
+
+	return run, nil
+}
+
+// testFlags returns 'go test' flags requested by repo config.
+func testFlags(cfg qfarm.BuildCfg) []string {
+	flags := []string{"-json"}
+	if cfg.Race {
+		flags = append(flags, "-race")
+	}
+	if cfg.Shuffle != "" {
+		flags = append(flags, "-shuffle="+cfg.Shuffle)
+	}
+	if cfg.Count > 0 {
+		flags = append(flags, fmt.Sprintf("-count=%d", cfg.Count))
+	}
+
+	return flags
+}
+
+// countTests returns number of passed, failed and flaky tests. Tests with subtests aren't counted,
+// so failure of the subtest doesn't count twice. Failed flaky tests count as passed if ignoreFlaky is set.
+func countTests(tests []qfarm.TestResult, ignoreFlaky bool) (passed, failed, flaky int) {
+	parents := parentTests(tests)
+	for _, t := range tests {
+		if parents[t.Package+"."+t.Name] {
+			continue
+		}
+
+		if t.Flaky {
+			flaky++
+		}
+
+		switch {
+		case t.Status == qfarm.TestPassed, t.Status == qfarm.TestFailed && t.Flaky && ignoreFlaky:
+			passed++
+		case t.Status == qfarm.TestFailed:
+			failed++
+		}
+	}
+
+	return passed, failed, flaky
+}
+
+// parentTests returns set of tests which have subtests, keyed by package and test name.
+func parentTests(tests []qfarm.TestResult) map[string]bool {
+	parents := make(map[string]bool)
+	for _, t := range tests {
+		if t.Parent != "" {
+			parents[t.Package+"."+t.Parent] = true
+		}
+	}
+
+	return parents
+}
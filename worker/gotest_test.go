@@ -0,0 +1,129 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qfarm/qfarm"
+)
+
+func TestParseTestEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  []string
+		want    []qfarm.TestResult
+		failed  bool
+		noTests bool
+	}{
+		{
+			name: "pass",
+			events: []string{
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"output","Package":"p","Test":"TestA","Output":"=== RUN   TestA\n"}`,
+				`{"Action":"pass","Package":"p","Test":"TestA","Elapsed":0.5}`,
+				`{"Action":"pass","Package":"p","Elapsed":0.6}`,
+			},
+			want: []qfarm.TestResult{
+				{Package: "p", Name: "TestA", Status: qfarm.TestPassed, Time: 500 * time.Millisecond, Output: "=== RUN   TestA\n"},
+			},
+		},
+		{
+			name: "count with pass and fail",
+			events: []string{
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"pass","Package":"p","Test":"TestA","Elapsed":0.25}`,
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"fail","Package":"p","Test":"TestA","Elapsed":0.25}`,
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"pass","Package":"p","Test":"TestA","Elapsed":0.25}`,
+				`{"Action":"fail","Package":"p","Elapsed":1}`,
+			},
+			want: []qfarm.TestResult{
+				{Package: "p", Name: "TestA", Status: qfarm.TestFailed, Time: 750 * time.Millisecond},
+			},
+			failed: true,
+		},
+		{
+			name: "count with fail first",
+			events: []string{
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"fail","Package":"p","Test":"TestA"}`,
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"pass","Package":"p","Test":"TestA"}`,
+				`{"Action":"fail","Package":"p"}`,
+			},
+			want: []qfarm.TestResult{
+				{Package: "p", Name: "TestA", Status: qfarm.TestFailed},
+			},
+			failed: true,
+		},
+		{
+			name: "count with skip and pass",
+			events: []string{
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"skip","Package":"p","Test":"TestA"}`,
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"pass","Package":"p","Test":"TestA"}`,
+			},
+			want: []qfarm.TestResult{
+				{Package: "p", Name: "TestA", Status: qfarm.TestPassed},
+			},
+		},
+		{
+			name: "subtests",
+			events: []string{
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"run","Package":"p","Test":"TestA/sub"}`,
+				`{"Action":"skip","Package":"p","Test":"TestA/sub"}`,
+				`{"Action":"pass","Package":"p","Test":"TestA"}`,
+			},
+			want: []qfarm.TestResult{
+				{Package: "p", Name: "TestA", Status: qfarm.TestPassed},
+				{Package: "p", Name: "TestA/sub", Parent: "TestA", Status: qfarm.TestSkipped},
+			},
+		},
+		{
+			name: "crash",
+			events: []string{
+				`# build output of older toolchain`,
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"output","Package":"p","Test":"TestA","Output":"panic: boom\n"}`,
+				`not a {json} line`,
+			},
+			want: []qfarm.TestResult{
+				{Package: "p", Name: "TestA", Status: qfarm.TestFailed, Output: "panic: boom\n"},
+			},
+			failed: true,
+		},
+		{
+			name: "no test files",
+			events: []string{
+				`{"Action":"output","Package":"p","Output":"?   \tp\t[no test files]\n"}`,
+				`{"Action":"skip","Package":"p"}`,
+			},
+			noTests: true,
+		},
+	}
+
+	for _, tt := range tests {
+		run, err := parseTestEvents(strings.NewReader(strings.Join(tt.events, "\n")))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+
+		if run.Failed != tt.failed || run.NoTests != tt.noTests {
+			t.Errorf("%s: got failed %v, no tests %v, want %v, %v", tt.name, run.Failed, run.NoTests, tt.failed, tt.noTests)
+		}
+		if len(run.Tests) != len(tt.want) {
+			t.Errorf("%s: got %d tests, want %d: %+v", tt.name, len(run.Tests), len(tt.want), run.Tests)
+			continue
+		}
+		for i, want := range tt.want {
+			if run.Tests[i] != want {
+				t.Errorf("%s: test %d: got %+v, want %+v", tt.name, i, run.Tests[i], want)
+			}
+		}
+	}
+}
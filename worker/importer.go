@@ -0,0 +1,188 @@
+package worker
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+)
+
+type sarifLog struct {
+	Runs []struct {
+		Tool struct {
+			Driver struct {
+				Name string `json:"name"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []struct {
+			RuleID  string `json:"ruleId"`
+			Level   string `json:"level"`
+			Message struct {
+				Text string `json:"text"`
+			} `json:"message"`
+			Locations []struct {
+				PhysicalLocation struct {
+					ArtifactLocation struct {
+						URI string `json:"uri"`
+					} `json:"artifactLocation"`
+					Region struct {
+						StartLine   int `json:"startLine"`
+						StartColumn int `json:"startColumn"`
+					} `json:"region"`
+				} `json:"physicalLocation"`
+			} `json:"locations"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+type checkstyleReport struct {
+	Files []struct {
+		Name   string `xml:"name,attr"`
+		Errors []struct {
+			Line     int    `xml:"line,attr"`
+			Column   int    `xml:"column,attr"`
+			Severity string `xml:"severity,attr"`
+			Message  string `xml:"message,attr"`
+			Source   string `xml:"source,attr"`
+		} `xml:"error"`
+	} `xml:"file"`
+}
+
+// parseIssues converts SARIF log or Checkstyle report into issues. If linter is empty, tool
+// reported in the file is used as linter of issues. Paths of issues are the same as in the file.
+func parseIssues(format, linter string, data []byte) ([]*qfarm.Issue, error) {
+	switch format {
+	case qfarm.LinterFormatSARIF:
+		return parseSARIF(linter, data)
+	case qfarm.LinterFormatCheckstyle:
+		return parseCheckstyle(linter, data)
+	}
+
+	return nil, fmt.Errorf("unknown format of linter output: %q", format)
+}
+
+func parseSARIF(linter string, data []byte) ([]*qfarm.Issue, error) {
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		return nil, fmt.Errorf("can't parse SARIF log: %v", err)
+	}
+
+	issues := make([]*qfarm.Issue, 0)
+	for _, run := range log.Runs {
+		name := linter
+		if name == "" {
+			name = run.Tool.Driver.Name
+		}
+
+		for _, r := range run.Results {
+			if len(r.Locations) == 0 {
+				continue
+			}
+			loc := r.Locations[0].PhysicalLocation
+
+			msg := r.Message.Text
+			if r.RuleID != "" && r.RuleID != name {
+				msg = r.RuleID + ": " + msg
+			}
+
+			severity := qfarm.Warning
+			switch r.Level {
+			case "error":
+				severity = qfarm.Error
+			case "note", "none":
+				severity = qfarm.Info
+			}
+
+			issues = append(issues, &qfarm.Issue{
+				Linter:   &qfarm.Linter{Name: name},
+				Severity: severity,
+				Path:     uriPath(loc.ArtifactLocation.URI),
+				Line:     loc.Region.StartLine,
+				Col:      loc.Region.StartColumn,
+				Message:  msg,
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+func parseCheckstyle(linter string, data []byte) ([]*qfarm.Issue, error) {
+	var report checkstyleReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("can't parse Checkstyle report: %v", err)
+	}
+
+	issues := make([]*qfarm.Issue, 0)
+	for _, f := range report.Files {
+		for _, e := range f.Errors {
+			name := linter
+			if name == "" {
+				name = e.Source
+			}
+			if name == "" {
+				name = qfarm.LinterFormatCheckstyle
+			}
+
+			severity := qfarm.Warning
+			switch e.Severity {
+			case "error":
+				severity = qfarm.Error
+			case "info", "ignore":
+				severity = qfarm.Info
+			}
+
+			issues = append(issues, &qfarm.Issue{
+				Linter:   &qfarm.Linter{Name: name},
+				Severity: severity,
+				Path:     f.Name,
+				Line:     e.Line,
+				Col:      e.Column,
+				Message:  e.Message,
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// uriPath converts SARIF artifact URI into file path.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || (u.Scheme != "" && u.Scheme != "file") {
+		return uri
+	}
+
+	return u.Path
+}
+
+// repoIssuePath converts path of the issue reported outside of the worker into path inside the
+// repo checkout. Relative paths are relative to the repo root, absolute paths have to contain repo
+// identifier, eg. /home/ci/go/src/github.com/qfarm/qfarm/worker/worker.go. Paths which lead
+// outside of the repo are rejected.
+func repoIssuePath(cfg qfarm.BuildCfg, path string) (string, bool) {
+	path = filepath.ToSlash(path)
+	switch {
+	case !filepath.IsAbs(path):
+		path = filepath.Join(cfg.Path, path)
+	case strings.HasPrefix(path, cfg.Path+"/"):
+		path = filepath.Clean(path)
+	default:
+		i := strings.Index(path, "/"+cfg.Repo+"/")
+		if i < 0 {
+			return "", false
+		}
+		path = filepath.Join(cfg.Path, path[i+len(cfg.Repo)+2:])
+	}
+
+	// cleaned path with ".." elements might lead outside of the repo
+	if !strings.HasPrefix(path, filepath.Clean(cfg.Path)+"/") {
+		return "", false
+	}
+
+	return path, true
+}
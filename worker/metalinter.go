@@ -2,7 +2,10 @@ package worker
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -15,9 +18,7 @@ import (
 
 	"github.com/google/shlex"
 	"github.com/qfarm/qfarm"
-	"log"
 	"github.com/qfarm/qfarm/redis"
-	"encoding/json"
 )
 
 const (
@@ -62,9 +63,7 @@ var (
 		Test:        `go test:^--- FAIL: .*$\s+(?P<path>[^:]+):(?P<line>\d+): (?P<message>.*)$`,                             // slow
 		Testify:     `go test:Location:\s+(?P<path>[^:]+):(?P<line>\d+)$\s+Error:\s+(?P<message>[^\n]+)`,                    // slow
 		Varcheck:    `varcheck .:^(?:[^:]+: )?(?P<path>[^:]+):(?P<line>\d+):(?P<col>\d+):\s*(?P<message>\w+)$`,              // slow
-		Vet:         "go tool vet ./*.go:PATH:LINE:MESSAGE",
-		Vetshadow:   "go tool vet --shadow ./*.go:PATH:LINE:MESSAGE",
-		Unconvert:   "unconvert .:PATH:LINE:COL:MESSAGE", // slow
+		Unconvert:   "unconvert .:PATH:LINE:COL:MESSAGE",                                                                    // slow
 	}
 	linterMessageOverrideFlag = map[string]string{
 		Errcheck:    "error return value not checked ({message})",
@@ -79,12 +78,16 @@ var (
 		Gotype:  "error",
 		Test:    "error",
 		Testify: "error",
-		Vet:     "error",
 	}
 	predefinedPatterns = map[string]string{
 		"PATH:LINE:COL:MESSAGE": `^(?P<path>[^\s][^\r\n:]+?\.go):(?P<line>\d+):(?P<col>\d+):\s*(?P<message>.*)$`,
 		"PATH:LINE:MESSAGE":     `^(?P<path>[^\s][^\r\n:]+?\.go):(?P<line>\d+):\s*(?P<message>.*)$`,
 	}
+	// linters with these patterns print SARIF log or Checkstyle report
+	predefinedFormats = map[string]string{
+		"SARIF":      qfarm.LinterFormatSARIF,
+		"CHECKSTYLE": qfarm.LinterFormatCheckstyle,
+	}
 	installMap = map[string]string{
 		Golint:      "github.com/golang/lint/golint",
 		Gotype:      "golang.org/x/tools/cmd/gotype",
@@ -114,15 +117,13 @@ var (
 		Ineffassign,
 		Interfacer,
 		Lll,
-		Vet,
-		Vetshadow,
 	}
 )
 
 type Metalinter struct {
 	cfg      *Cfg
 	notifier *Notifier
-	redis *redis.Service
+	redis    *redis.Service
 }
 
 func NewMetalinter(cfg *Cfg, redis *redis.Service, notifier *Notifier) *Metalinter {
@@ -137,79 +138,244 @@ const (
 	Error   Severity = "error"
 )
 
+// LinterFromName returns one of built-in linters.
 func LinterFromName(name string) (*qfarm.Linter, error) {
-	s := linters[name]
+	s, ok := linters[name]
+	if !ok {
+		return nil, fmt.Errorf("linter %s doesn't exist", name)
+	}
+
 	parts := strings.SplitN(s, ":", 2)
-	pattern := parts[1]
-	if p, ok := predefinedPatterns[pattern]; ok {
-		pattern = p
+	return newLinter(qfarm.CustomLinter{
+		Name:            name,
+		Command:         parts[0],
+		Pattern:         parts[1],
+		Severity:        qfarm.Severity(linterSeverityFlag[name]),
+		MessageOverride: linterMessageOverrideFlag[name],
+		InstallFrom:     installMap[name],
+	})
+}
+
+// newLinter creates linter from its definition.
+func newLinter(def qfarm.CustomLinter) (*qfarm.Linter, error) {
+	if def.Name == "" || def.Command == "" || def.Pattern == "" {
+		return nil, fmt.Errorf("linter %q should have name, command and pattern", def.Name)
 	}
-	re, err := regexp.Compile("(?m:" + pattern + ")")
+	if def.Severity != "" && !def.Severity.Valid() {
+		return nil, fmt.Errorf("invalid severity %q of linter %s", def.Severity, def.Name)
+	}
+
+	l := &qfarm.Linter{
+		Name:             def.Name,
+		Command:          def.Command,
+		InstallFrom:      def.InstallFrom,
+		SeverityOverride: def.Severity,
+		MessageOverride:  def.MessageOverride,
+		EventType:        linterEventType(def.Name),
+	}
+
+	if format, ok := predefinedFormats[def.Pattern]; ok {
+		l.Format = format
+		return l, nil
+	}
+
+	l.Pattern = def.Pattern
+	if p, ok := predefinedPatterns[l.Pattern]; ok {
+		l.Pattern = p
+	}
+
+	var err error
+	l.Regex, err = regexp.Compile("(?m:" + l.Pattern + ")")
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid pattern of linter %s: %v", def.Name, err)
 	}
 
-	return &qfarm.Linter{
-		Name:             name,
-		Command:          s[0:strings.Index(s, ":")],
-		Pattern:          pattern,
-		InstallFrom:      installMap[name],
-		SeverityOverride: qfarm.Severity(linterSeverityFlag[name]),
-		MessageOverride:  linterMessageOverrideFlag[name],
-		Regex:            re,
-		EventType:        linterEventsMapping[name],
-	}, nil
+	return l, nil
 }
 
-func (m *Metalinter) Start(cfg qfarm.BuildCfg, buildNo int, ft *FilesMap) error {
+// Start runs all linters configured for the build and returns found issues together with names
+// of linters which were killed because they exceeded linter timeout.
+func (m *Metalinter) Start(ctx context.Context, cfg qfarm.BuildCfg) ([]*qfarm.Issue, []string, error) {
 	start := time.Now()
 	paths := m.expandPaths([]string{cfg.Path + "/..."}, cfg.SkipDirs)
 
 	m.debug("Analyzing following paths: %v", paths)
 
-	linters := m.linters(cfg.Linters)
-	issues, errch := m.runLinters(linters, cfg.Repo, paths, m.cfg.Concurrency, cfg.IncludeTests)
+	linterTimeout, _ := m.cfg.Timeouts(cfg)
+	checkers := m.checkers(cfg, m.cfg.Concurrency, linterTimeout)
+	issues, errch := m.runCheckers(ctx, checkers, cfg.Repo, paths)
 
+	found := make([]*qfarm.Issue, 0)
 	for issue := range issues {
-		if strings.HasSuffix(issue.Path, ".gen.go") || strings.HasSuffix(issue.Path, ".pb.go") || strings.Contains(issue.Path, ".git") || strings.Contains(issue.Path, ".idea") || strings.Contains(issue.Path, "vendor") || strings.Contains(issue.Path, "Godeps") {
+		if !skipIssue(issue) {
+			found = append(found, issue)
+		}
+	}
+
+	timedOut := map[string]bool{}
+	for err := range errch {
+		if e, ok := err.(*linterTimeoutError); ok {
+			timedOut[e.linter] = true
+		}
+		warning("%s", err)
+	}
+
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+
+	timedOutLinters := make([]string, 0, len(timedOut))
+	for name := range timedOut {
+		timedOutLinters = append(timedOutLinters, name)
+	}
+	sort.Strings(timedOutLinters)
+
+	elapsed := time.Now().Sub(start)
+	m.debug("total elapsed time %s", elapsed)
+
+	return found, timedOutLinters, nil
+}
+
+// linterTimeoutError is returned when linter was killed because it exceeded linter timeout.
+type linterTimeoutError struct {
+	linter string
+	path   string
+}
+
+func (e *linterTimeoutError) Error() string {
+	return fmt.Sprintf("linter %s timed out on %s", e.linter, e.path)
+}
+
+// Import returns issues uploaded for the analyzed commit, they are stored together with issues
+// of linters run by the worker. Uploads which can't be parsed are skipped.
+func (m *Metalinter) Import(cfg qfarm.BuildCfg, uploads []qfarm.IssuesUpload) []*qfarm.Issue {
+	found := make([]*qfarm.Issue, 0)
+	for _, u := range uploads {
+		issues, err := parseIssues(u.Format, u.Linter, u.Data)
+		if err != nil {
+			warning("Can't import issues uploaded for repo %s: %v", cfg.Repo, err)
 			continue
 		}
 
-		// apply issue to all parents in file tree
+		for _, issue := range issues {
+			path, ok := repoIssuePath(cfg, issue.Path)
+			if !ok {
+				m.debug("Issue outside of the repo: %s", issue)
+				continue
+			}
+			issue.Path = path
+
+			if !skipIssue(issue) {
+				found = append(found, issue)
+			}
+		}
+	}
+
+	return found
+}
+
+// Store applies issues of the build to all parents in file tree and stores them. Issues are
+// suppressed per linter first, then duplicates reported by several linters are merged, so issue
+// suppressed for one linter is still counted if another linter reported it. Suppressed issues are
+// only stored. If baseline is given, issues are compared with it and fixed issues of the baseline
+// are stored as well.
+func (m *Metalinter) Store(cfg qfarm.BuildCfg, buildNo int, ft *FilesMap, sup *suppressor, base *qfarm.Baseline, issues []*qfarm.Issue) (*qfarm.BaselineDiff, error) {
+	counted := make([]*qfarm.Issue, 0, len(issues))
+	suppressed := make([]*qfarm.Issue, 0)
+	for _, issue := range issues {
+		if sup.suppress(issue) {
+			suppressed = append(suppressed, issue)
+		} else {
+			counted = append(counted, issue)
+		}
+	}
+
+	counted = dedupIssues(counted)
+	suppressed = dedupIssues(suppressed)
+	sup.count += len(suppressed)
+	issues = append(counted, suppressed...)
+	m.debug("%d issues after merging duplicates", len(issues))
+
+	// severities of merged issues are set by all linters which reported them
+	setSeverities(cfg, issues)
+
+	qfarm.SetFingerprints(issues, cfg.Path, ft.codeContext())
+
+	var diff *qfarm.BaselineDiff
+	var fixed []*qfarm.Issue
+	if base != nil {
+		var err error
+		diff, fixed, err = compareBaseline(base, cfg.Path, issues, counted)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, issue := range counted {
 		if err := ft.ApplyIssue(issue); err != nil {
-			return err
+			return nil, err
 		}
+	}
 
-		// trim path in json
-		issue.Path = strings.Replace(issue.Path, cfg.Path, "", -1)
+	for _, issue := range issues {
+		if err := storeIssue(m.redis, cfg, buildNo, issue); err != nil {
+			return nil, err
+		}
+	}
 
-		// marshal issue to json
+	for _, issue := range fixed {
 		data, err := json.Marshal(issue)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		// store issue in global list of issues
-		_, err = m.redis.SortedSetAdd(fmt.Sprintf("issues:%s:%d", cfg.Repo, buildNo), data, issue.Severity.Rank())
-		if err != nil {
-			return err
+		if _, err := m.redis.SortedSetAdd(fmt.Sprintf("issues:%s:%d:fixed", cfg.Repo, buildNo), data, issue.Severity.Rank()); err != nil {
+			return nil, err
 		}
+	}
+
+	return diff, nil
+}
+
+// skipIssue checks whether issue was found in generated or vendored code.
+func skipIssue(issue *qfarm.Issue) bool {
+	return strings.HasSuffix(issue.Path, ".gen.go") || strings.HasSuffix(issue.Path, ".pb.go") || strings.Contains(issue.Path, ".git") || strings.Contains(issue.Path, ".idea") || strings.Contains(issue.Path, "vendor") || strings.Contains(issue.Path, "Godeps")
+}
 
-		// store issue in specified list of issues
-		_, err = m.redis.SortedSetAdd(fmt.Sprintf("issues:%s:%d:%s", cfg.Repo, buildNo, issue.Severity), data, issue.Severity.Rank())
+// storeIssue stores the issue in lists of issues of the build. Suppressed issues are stored
+// in separate list.
+func storeIssue(r *redis.Service, cfg qfarm.BuildCfg, buildNo int, issue *qfarm.Issue) error {
+	// trim path in json
+	issue.Path = strings.Replace(issue.Path, cfg.Path, "", -1)
+
+	// marshal issue to json
+	data, err := json.Marshal(issue)
+	if err != nil {
+		return err
+	}
+
+	if issue.Suppressed != "" {
+		_, err = r.SortedSetAdd(fmt.Sprintf("issues:%s:%d:suppressed", cfg.Repo, buildNo), data, issue.Severity.Rank())
+		return err
+	}
+
+	// store issue in list of new or existing issues in baseline mode
+	if issue.Baseline != "" {
+		_, err = r.SortedSetAdd(fmt.Sprintf("issues:%s:%d:%s", cfg.Repo, buildNo, issue.Baseline), data, issue.Severity.Rank())
 		if err != nil {
 			return err
 		}
 	}
 
-	for err := range errch {
-		warning("%s", err)
+	// store issue in global list of issues
+	_, err = r.SortedSetAdd(fmt.Sprintf("issues:%s:%d", cfg.Repo, buildNo), data, issue.Severity.Rank())
+	if err != nil {
+		return err
 	}
 
-	elapsed := time.Now().Sub(start)
-	m.debug("total elapsed time %s", elapsed)
-
-	return nil
+	// store issue in specified list of issues
+	_, err = r.SortedSetAdd(fmt.Sprintf("issues:%s:%d:%s", cfg.Repo, buildNo, issue.Severity), data, issue.Severity.Rank())
+	return err
 }
 
 func (m *Metalinter) debug(format string, args ...interface{}) {
@@ -245,52 +411,42 @@ func (v Vars) Replace(s string) string {
 	return s
 }
 
-func (m *Metalinter) runLinters(linters map[string]*qfarm.Linter, repo string, paths []string, concurrency int, includeTests bool) (chan *qfarm.Issue, chan error) {
-	errch := make(chan error, len(linters)*len(paths))
+// checker finds issues in the analyzed paths. External linters and in-process analyzers
+// implement it, so both run side by side.
+type checker interface {
+	name() string
+	eventType() string
+
+	// check sends found issues and errors to channels and returns when it's done
+	check(ctx context.Context, paths []string, issues chan<- *qfarm.Issue, errch chan<- error)
+}
+
+// checkers returns all checkers of the build. They share limit of concurrently run processes.
+func (m *Metalinter) checkers(cfg qfarm.BuildCfg, concurrency int, timeout time.Duration) []checker {
 	concurrencych := make(chan bool, concurrency)
+
+	var out []checker
+	for _, linter := range m.linters(cfg) {
+		out = append(out, &externalLinter{m: m, linter: linter, cfg: cfg, concurrency: concurrencych, timeout: timeout})
+	}
+	if c := m.newAnalysisChecker(cfg, concurrencych, timeout); c != nil {
+		out = append(out, c)
+	}
+
+	return out
+}
+
+func (m *Metalinter) runCheckers(ctx context.Context, checkers []checker, repo string, paths []string) (chan *qfarm.Issue, chan error) {
+	errch := make(chan error, len(checkers)*len(paths))
 	incomingIssues := make(chan *qfarm.Issue, 1000000)
 	wg := &sync.WaitGroup{}
-	for _, linter := range linters {
-		// Recreated in each loop because it is mutated by executeLinter().
-		vars := Vars{
-			"duplthreshold":   fmt.Sprintf("%d", m.cfg.DuplThreshold),
-			"mincyclo":        fmt.Sprintf("%d", m.cfg.Cyclo),
-			"maxlinelength":   fmt.Sprintf("%d", m.cfg.LLLineLength),
-			"min_confidence":  fmt.Sprintf("%f", m.cfg.GolintMinConfidence),
-			"min_occurrences": fmt.Sprintf("%d", m.cfg.GoconstMinOccurrences),
-			"tests":           "",
-		}
-		if includeTests {
-			vars["tests"] = "-t"
-		}
-
-		// wait group for single linter.
-		wgl := sync.WaitGroup{}
-		for _, path := range paths {
-			wg.Add(1)
-			wgl.Add(1)
-			state := &linterState{
-				Linter: linter,
-				issues: incomingIssues,
-				path:   path,
-				vars:   vars.Copy(),
-				repo:   repo,
-			}
-			go func() {
-				concurrencych <- true
-				err := m.executeLinter(state)
-				if err != nil {
-					errch <- err
-				}
-				<-concurrencych
-				wg.Done()
-				wgl.Done()
-			}()
-		}
-		go func(repo, linterName, eventType string) {
-			wgl.Wait()
-			m.notifier.SendEvent(repo, fmt.Sprintf("Linter %s finished!", linterName), eventType)
-		}(repo, linter.Name, linter.EventType)
+	for _, c := range checkers {
+		wg.Add(1)
+		go func(c checker) {
+			c.check(ctx, paths, incomingIssues, errch)
+			m.notifier.SendEvent(repo, fmt.Sprintf("Linter %s finished!", c.name()), c.eventType())
+			wg.Done()
+		}(c)
 	}
 
 	go func() {
@@ -301,6 +457,86 @@ func (m *Metalinter) runLinters(linters map[string]*qfarm.Linter, repo string, p
 	return incomingIssues, errch
 }
 
+// linterVars returns variables of linter commands. Parameters set by the repo config override
+// worker config.
+func (m *Metalinter) linterVars(cfg qfarm.BuildCfg) Vars {
+	params := cfg.LinterParams
+	if params.DuplThreshold <= 0 {
+		params.DuplThreshold = m.cfg.DuplThreshold
+	}
+	if params.MinCyclo <= 0 {
+		params.MinCyclo = m.cfg.Cyclo
+	}
+	if params.MaxLineLength <= 0 {
+		params.MaxLineLength = m.cfg.LLLineLength
+	}
+	if params.MinConfidence <= 0 {
+		params.MinConfidence = m.cfg.GolintMinConfidence
+	}
+	if params.MinOccurrences <= 0 {
+		params.MinOccurrences = m.cfg.GoconstMinOccurrences
+	}
+
+	vars := Vars{
+		"duplthreshold":   fmt.Sprintf("%d", params.DuplThreshold),
+		"mincyclo":        fmt.Sprintf("%d", params.MinCyclo),
+		"maxlinelength":   fmt.Sprintf("%d", params.MaxLineLength),
+		"min_confidence":  fmt.Sprintf("%f", params.MinConfidence),
+		"min_occurrences": fmt.Sprintf("%d", params.MinOccurrences),
+		"tests":           "",
+	}
+	if cfg.IncludeTests {
+		vars["tests"] = "-t"
+	}
+
+	return vars
+}
+
+// externalLinter runs linter binary in every analyzed path and parses its output.
+type externalLinter struct {
+	m           *Metalinter
+	linter      *qfarm.Linter
+	cfg         qfarm.BuildCfg
+	concurrency chan bool
+	timeout     time.Duration
+}
+
+func (l *externalLinter) name() string {
+	return l.linter.Name
+}
+
+func (l *externalLinter) eventType() string {
+	return l.linter.EventType
+}
+
+func (l *externalLinter) check(ctx context.Context, paths []string, issues chan<- *qfarm.Issue, errch chan<- error) {
+	vars := l.m.linterVars(l.cfg)
+
+	wg := sync.WaitGroup{}
+	for _, path := range paths {
+		wg.Add(1)
+		state := &linterState{
+			Linter: l.linter,
+			issues: issues,
+			path:   path,
+			// copied because it is mutated by executeLinter()
+			vars: vars.Copy(),
+			repo: l.cfg.Repo,
+			env:  buildEnv(l.cfg),
+		}
+		go func() {
+			l.concurrency <- true
+			err := l.m.executeLinter(ctx, state, l.timeout)
+			if err != nil {
+				errch <- err
+			}
+			<-l.concurrency
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
 func (m *Metalinter) expandPaths(paths, skip []string) []string {
 	if len(paths) == 0 {
 		paths = []string{"."}
@@ -359,6 +595,11 @@ func (m *Metalinter) InstallAllLinters() error {
 	for _, v := range installMap {
 		linters = append(linters, v)
 	}
+	for _, l := range m.cfg.CustomLinters {
+		if l.InstallFrom != "" {
+			linters = append(linters, l.InstallFrom)
+		}
+	}
 
 	cmd := m.makeInstallCommand(linters...)
 	c := exec.Command("go", cmd...)
@@ -370,9 +611,10 @@ func (m *Metalinter) InstallAllLinters() error {
 type linterState struct {
 	*qfarm.Linter
 	path   string
-	issues chan *qfarm.Issue
+	issues chan<- *qfarm.Issue
 	vars   Vars
 	repo   string
+	env    []string
 }
 
 func (l *linterState) InterpolatedCommand() string {
@@ -380,7 +622,7 @@ func (l *linterState) InterpolatedCommand() string {
 	return l.vars.Replace(l.Command)
 }
 
-func parseCommand(dir, command string) (string, []string, error) {
+func parseCommand(dir, command string, env []string) (string, []string, error) {
 	args, err := shlex.Split(command)
 	if err != nil {
 		return "", nil, err
@@ -388,7 +630,7 @@ func parseCommand(dir, command string) (string, []string, error) {
 	if len(args) == 0 {
 		return "", nil, fmt.Errorf("invalid command %q", command)
 	}
-	exe, err := exec.LookPath(args[0])
+	exe, err := lookPath(args[0], env)
 	if err != nil {
 		return "", nil, err
 	}
@@ -413,12 +655,16 @@ func parseCommand(dir, command string) (string, []string, error) {
 	return exe, out, nil
 }
 
-func (m *Metalinter) executeLinter(state *linterState) error {
+func (m *Metalinter) executeLinter(ctx context.Context, state *linterState, timeout time.Duration) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
 	m.debug("linting with %s: %s (on %s)", state.Name, state.Command, state.path)
 
 	start := time.Now()
 	command := state.InterpolatedCommand()
-	exe, args, err := parseCommand(state.path, command)
+	exe, args, err := parseCommand(state.path, command, state.env)
 	if err != nil {
 		return err
 	}
@@ -426,21 +672,23 @@ func (m *Metalinter) executeLinter(state *linterState) error {
 	buf := bytes.NewBuffer(nil)
 	cmd := exec.Command(exe, args...)
 	cmd.Dir = state.path
+	cmd.Env = state.env
 	cmd.Stdout = buf
 	cmd.Stderr = buf
-	err = cmd.Start()
-	if err != nil {
-		return fmt.Errorf("failed to execute linter %s: %s", command, err)
-	}
-
-	done := make(chan bool)
-	go func() {
-		err = cmd.Wait()
-		done <- true
-	}()
 
 	// Wait for process to complete or deadline to expire.
-	<-done
+	linterCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err = runCommand(linterCtx, cmd)
+	if linterCtx.Err() != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &linterTimeoutError{linter: state.Name, path: state.path}
+	}
+	if _, ok := err.(*exec.ExitError); !ok && err != nil {
+		return fmt.Errorf("failed to execute linter %s: %s", command, err)
+	}
 
 	if err != nil {
 		m.debug("warning: %s returned %s", command, err)
@@ -473,21 +721,69 @@ func (l *linterState) fixPath(path string) string {
 	return path
 }
 
-func (m *Metalinter) linters(cfgLinters []string) map[string]*qfarm.Linter {
+// linters returns linters requested by the build. Besides built-in linters, custom linters
+// defined in worker config and, if worker config permits it, in repo config might be used.
+// Linters defined in repo config are always run.
+func (m *Metalinter) linters(cfg qfarm.BuildCfg) map[string]*qfarm.Linter {
+	defs := map[string]qfarm.CustomLinter{}
+	for _, def := range m.cfg.CustomLinters {
+		defs[def.Name] = def
+	}
+
+	names := cfg.Linters
+	if len(cfg.CustomLinters) > 0 && !m.cfg.AllowRepoLinters {
+		warning("Custom linters of repo %s aren't allowed by worker config!", cfg.Repo)
+	} else {
+		for _, def := range cfg.CustomLinters {
+			if _, ok := defs[def.Name]; ok {
+				continue
+			}
+			defs[def.Name] = def
+			names = append(names, def.Name)
+		}
+	}
+
 	out := map[string]*qfarm.Linter{}
-	for _, name := range cfgLinters {
-		_, ok := linters[name]
-		if ok {
-			out[name], _ = LinterFromName(name)
+	for _, name := range names {
+		var l *qfarm.Linter
+		var err error
+		if _, ok := linters[name]; ok {
+			l, err = LinterFromName(name)
+		} else if def, ok := defs[name]; ok {
+			l, err = newLinter(def)
 		} else {
 			warning("Linter %s doesn't exist!", name)
+			continue
+		}
+
+		if err != nil {
+			warning("Can't use linter %s: %v", name, err)
+			continue
 		}
+		out[name] = l
 	}
 
 	return out
 }
 
 func (m *Metalinter) processOutput(state *linterState, out []byte) error {
+	if state.Format != "" {
+		issues, err := parseIssues(state.Format, state.Name, out)
+		if err != nil {
+			return fmt.Errorf("can't parse output of linter %s: %v", state.Name, err)
+		}
+
+		m.debug("%s hits %d: %s", state.Name, len(issues), state.Format)
+		for _, issue := range issues {
+			issue.Path = state.fixPath(issue.Path)
+			if state.SeverityOverride != "" {
+				issue.Severity = state.SeverityOverride
+			}
+			state.issues <- issue
+		}
+		return nil
+	}
+
 	re := state.Regex
 	all := re.FindAllSubmatchIndex(out, -1)
 	m.debug("%s hits %d: %s", state.Name, len(all), state.Pattern)
@@ -499,7 +795,7 @@ func (m *Metalinter) processOutput(state *linterState, out []byte) error {
 		}
 
 		issue := &qfarm.Issue{Line: 1}
-		issue.Linter, _ = LinterFromName(state.Name)
+		issue.Linter = state.Linter
 		for i, name := range re.SubexpNames() {
 			part := string(group[i])
 			if name != "" {
@@ -528,13 +824,13 @@ func (m *Metalinter) processOutput(state *linterState, out []byte) error {
 			case "":
 			}
 		}
-		if m, ok := linterMessageOverrideFlag[state.Name]; ok {
-			issue.Message = state.vars.Replace(m)
+		if state.MessageOverride != "" {
+			issue.Message = state.vars.Replace(state.MessageOverride)
 		}
-		if sev, ok := linterSeverityFlag[state.Name]; ok {
-			issue.Severity = qfarm.Severity(sev)
+		if state.SeverityOverride != "" {
+			issue.Severity = state.SeverityOverride
 		} else {
-			issue.Severity = "warning"
+			issue.Severity = qfarm.Warning
 		}
 		state.issues <- issue
 	}
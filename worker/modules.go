@@ -0,0 +1,182 @@
+package worker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+)
+
+// FindModules returns all Go modules in the repo. Empty list means that repo uses GOPATH layout.
+func FindModules(repoPath string) ([]qfarm.Module, error) {
+	modules := make([]qfarm.Module, 0)
+	err := filepath.Walk(repoPath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		base := info.Name()
+		if info.IsDir() {
+			if p != repoPath && (base == "vendor" || base == "testdata" || strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if base != "go.mod" {
+			return nil
+		}
+
+		modPath, err := modulePath(p)
+		if err != nil {
+			return err
+		}
+
+		dir, err := filepath.Rel(repoPath, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+
+		modules = append(modules, qfarm.Module{Path: modPath, Dir: dir})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(byDir(modules))
+	return modules, nil
+}
+
+// modulePath reads module path from go.mod file.
+func modulePath(goMod string) (string, error) {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if !strings.HasPrefix(line, "module") {
+			continue
+		}
+
+		path := strings.TrimSpace(strings.TrimPrefix(line, "module"))
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			path = unquoted
+		}
+		if path != "" {
+			return path, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("can't find module path in %s", goMod)
+}
+
+// moduleOf returns the innermost module which contains file or directory with given path
+// relative to the repo root.
+func moduleOf(modules []qfarm.Module, relPath string) (qfarm.Module, bool) {
+	relPath = filepath.Clean(relPath)
+	found, depth := -1, -1
+	for i, m := range modules {
+		if m.Dir != "." && relPath != m.Dir && !strings.HasPrefix(relPath, m.Dir+string(filepath.Separator)) {
+			continue
+		}
+
+		d := 0
+		if m.Dir != "." {
+			d = len(strings.Split(m.Dir, string(filepath.Separator)))
+		}
+		if d > depth {
+			found, depth = i, d
+		}
+	}
+
+	if found < 0 {
+		return qfarm.Module{}, false
+	}
+	return modules[found], true
+}
+
+// moduleReports summarizes coverage and issues of every module of the build.
+func moduleReports(cfg qfarm.BuildCfg, ft *FilesMap, cover *qfarm.CoverageReport) []qfarm.ModuleReport {
+	if len(cfg.Modules) == 0 {
+		return nil
+	}
+
+	reports := make([]qfarm.ModuleReport, len(cfg.Modules))
+	index := make(map[string]int)
+	for i, m := range cfg.Modules {
+		reports[i].Module = m
+		index[m.Path] = i
+	}
+
+	// issues are counted on files, directories hold totals of nested modules as well
+	for p, node := range ft.FilesMap {
+		if node.Dir {
+			continue
+		}
+
+		rel, err := filepath.Rel(cfg.Path, p)
+		if err != nil {
+			continue
+		}
+
+		m, ok := moduleOf(cfg.Modules, rel)
+		if !ok {
+			continue
+		}
+
+		r := &reports[index[m.Path]]
+		r.IssuesNo += node.IssuesNo
+		r.ErrorsNo += node.ErrorsNo
+		r.WarningsNo += node.WarningsNo
+		r.InfosNo += node.InfosNo
+	}
+
+	if cover == nil {
+		return reports
+	}
+
+	numStmt := make([]int64, len(reports))
+	covered := make([]int64, len(reports))
+	for _, pkg := range cover.Packages {
+		i, ok := index[pkg.Module]
+		if !ok {
+			continue
+		}
+
+		reports[i].TestsNo += pkg.TestsNo
+		reports[i].PassedNo += pkg.PassedNo
+		reports[i].FailedNo += pkg.FailedNo
+		numStmt[i] += pkg.NumStmt
+		covered[i] += pkg.Covered
+	}
+
+	for i := range reports {
+		if numStmt[i] > 0 {
+			reports[i].Coverage = float64(covered[i]) / float64(numStmt[i]) * 100
+		}
+	}
+
+	return reports
+}
+
+type byDir []qfarm.Module
+
+func (m byDir) Len() int           { return len(m) }
+func (m byDir) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m byDir) Less(i, j int) bool { return m[i].Dir < m[j].Dir }
@@ -0,0 +1,246 @@
+package worker
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+)
+
+// nolint directive, eg. "//nolint", "//nolint:golint,dupl" or "//nolint:dupl // generated tables"
+var nolintDirective = regexp.MustCompile(`^//\s?nolint(?::([\w-]+(?:\s*,\s*[\w-]+)*))?(?:\s*//\s*(.*))?\s*$`)
+
+// nolintRange is a range of lines in which issues of linters are suppressed.
+type nolintRange struct {
+	from, to int
+
+	// all linters if empty
+	linters []string
+	reason  string
+}
+
+func (r nolintRange) matches(issue *qfarm.Issue) bool {
+	if issue.Line < r.from || issue.Line > r.to {
+		return false
+	}
+	if len(r.linters) == 0 {
+		return true
+	}
+
+	for _, l := range r.linters {
+		if l == "all" || reportedBy(issue, l) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// excludeRule is a compiled exclude rule of the repo config.
+type excludeRule struct {
+	qfarm.ExcludeRule
+	no      int
+	message *regexp.Regexp
+}
+
+func (r excludeRule) matches(relPath string, issue *qfarm.Issue) bool {
+	if len(r.Linters) > 0 {
+		found := false
+		for _, l := range r.Linters {
+			if reportedBy(issue, l) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if r.Path != "" && !matchPath(r.Path, relPath) {
+		return false
+	}
+
+	return r.message == nil || r.message.MatchString(issue.Message)
+}
+
+// matchPath checks whether path relative to the repo root matches the glob. Glob without slash
+// matches file name.
+func matchPath(glob, relPath string) bool {
+	name := relPath
+	if !strings.Contains(glob, "/") {
+		name = filepath.Base(relPath)
+	}
+
+	ok, _ := filepath.Match(glob, name)
+	return ok
+}
+
+// suppressor finds issues suppressed by nolint directives in the code or by exclude rules of
+// the repo config. Suppressed issues aren't counted, but are kept for audit.
+type suppressor struct {
+	root  string
+	rules []excludeRule
+	files map[string][]nolintRange
+
+	// number of suppressed issues
+	count int
+}
+
+// newSuppressor creates suppressor for the build. Invalid exclude rules are skipped.
+func newSuppressor(cfg qfarm.BuildCfg) *suppressor {
+	s := &suppressor{root: cfg.Path, files: make(map[string][]nolintRange)}
+	for i, rule := range cfg.Exclude {
+		r := excludeRule{ExcludeRule: rule, no: i + 1}
+		if rule.Message != "" {
+			re, err := regexp.Compile(rule.Message)
+			if err != nil {
+				warning("Invalid message of exclude rule %d of repo %s: %v", r.no, cfg.Repo, err)
+				continue
+			}
+			r.message = re
+		}
+		if _, err := filepath.Match(rule.Path, ""); err != nil {
+			warning("Invalid path of exclude rule %d of repo %s: %v", r.no, cfg.Repo, err)
+			continue
+		}
+
+		s.rules = append(s.rules, r)
+	}
+
+	return s
+}
+
+// suppress checks whether issue with path inside the repo is suppressed. If so, it sets
+// the reason of suppression in the issue.
+func (s *suppressor) suppress(issue *qfarm.Issue) bool {
+	reason, ok := s.reason(issue)
+	if ok {
+		issue.Suppressed = reason
+	}
+
+	return ok
+}
+
+func (s *suppressor) reason(issue *qfarm.Issue) (string, bool) {
+	ranges, ok := s.files[issue.Path]
+	if !ok && strings.HasSuffix(issue.Path, ".go") {
+		var err error
+		ranges, err = parseNolint(issue.Path)
+		if err != nil {
+			warning("Can't read nolint directives: %v", err)
+		}
+		s.files[issue.Path] = ranges
+	}
+
+	for _, r := range ranges {
+		if r.matches(issue) {
+			if r.reason != "" {
+				return "nolint: " + r.reason, true
+			}
+			return "nolint", true
+		}
+	}
+
+	rel, err := filepath.Rel(s.root, issue.Path)
+	if err != nil {
+		rel = issue.Path
+	}
+	for _, r := range s.rules {
+		if r.matches(filepath.ToSlash(rel), issue) {
+			return fmt.Sprintf("exclude rule %d", r.no), true
+		}
+	}
+
+	return "", false
+}
+
+// parseNolint returns ranges of lines suppressed by nolint directives in the Go file. Directive
+// at the end of the line suppresses issues of that line, directive on its own line suppresses
+// issues of the whole declaration or statement which follows it. Directive above the package
+// clause suppresses issues of the whole file.
+func parseNolint(path string) ([]nolintRange, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if f == nil {
+		return nil, err
+	}
+
+	// line of the following node of standalone directives
+	pending := make(map[int][]*nolintRange)
+	var out []*nolintRange
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			m := nolintDirective.FindStringSubmatch(c.Text)
+			if m == nil {
+				continue
+			}
+
+			r := &nolintRange{reason: m[2]}
+			for _, l := range strings.Split(m[1], ",") {
+				if l = strings.TrimSpace(l); l != "" {
+					r.linters = append(r.linters, l)
+				}
+			}
+
+			pos := fset.Position(c.Slash)
+			r.from, r.to = pos.Line, pos.Line
+			switch {
+			case c.Slash < f.Package:
+				r.from, r.to = 1, math.MaxInt32
+			case standalone(src, pos.Offset):
+				next := fset.Position(group.End()).Line + 1
+				pending[next] = append(pending[next], r)
+			}
+			out = append(out, r)
+		}
+	}
+
+	if len(pending) > 0 {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if n == nil {
+				return false
+			}
+
+			line := fset.Position(n.Pos()).Line
+			if ranges, ok := pending[line]; ok {
+				// the outermost node starting at the line is visited first
+				for _, r := range ranges {
+					r.to = fset.Position(n.End()).Line
+				}
+				delete(pending, line)
+			}
+			return true
+		})
+	}
+
+	ranges := make([]nolintRange, 0, len(out))
+	for _, r := range out {
+		ranges = append(ranges, *r)
+	}
+
+	return ranges, nil
+}
+
+// standalone checks whether there is no code before the offset in its line.
+func standalone(src []byte, offset int) bool {
+	for i := offset - 1; i >= 0 && src[i] != '\n'; i-- {
+		if src[i] != ' ' && src[i] != '\t' {
+			return false
+		}
+	}
+
+	return true
+}
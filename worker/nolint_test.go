@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qfarm/qfarm"
+)
+
+const nolintSrc = `package p
+
+func a() {
+	x := 1 //nolint:golint,dupl // generated
+	_ = x
+}
+
+//nolint:errcheck
+func b() {
+	f()
+	f()
+}
+
+func c() {
+	//nolint
+	if true {
+		f()
+	}
+	f()
+}
+
+func f() error { return nil }
+`
+
+func TestParseNolint(t *testing.T) {
+	path := writeTempGo(t, nolintSrc)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ranges, err := parseNolint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []nolintRange{
+		{from: 4, to: 4, linters: []string{"golint", "dupl"}, reason: "generated"},
+		{from: 8, to: 12, linters: []string{"errcheck"}},
+		{from: 15, to: 18},
+	}
+	if len(ranges) != len(want) {
+		t.Fatalf("got ranges %+v, want %+v", ranges, want)
+	}
+	for i, r := range ranges {
+		w := want[i]
+		if r.from != w.from || r.to != w.to || r.reason != w.reason || len(r.linters) != len(w.linters) {
+			t.Errorf("range %d: got %+v, want %+v", i, r, w)
+			continue
+		}
+		for j := range r.linters {
+			if r.linters[j] != w.linters[j] {
+				t.Errorf("range %d: got %+v, want %+v", i, r, w)
+			}
+		}
+	}
+}
+
+func TestParseNolintFile(t *testing.T) {
+	path := writeTempGo(t, "//nolint:dupl\npackage p\n\nvar a = 1\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ranges, err := parseNolint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 || ranges[0].from != 1 || ranges[0].to < 4 {
+		t.Fatalf("got ranges %+v, want single range of the whole file", ranges)
+	}
+}
+
+func TestSuppress(t *testing.T) {
+	path := writeTempGo(t, nolintSrc)
+	root := filepath.Dir(path)
+	defer os.RemoveAll(root)
+
+	cfg := qfarm.BuildCfg{Path: root, Exclude: []qfarm.ExcludeRule{
+		{Linters: []string{"gocyclo"}, Path: "*.go"},
+		{Linters: []string{"race"}, Path: "p.go"},
+	}}
+	tests := []struct {
+		linter string
+		line   int
+		want   string
+	}{
+		// end of line directive
+		{"golint", 4, "nolint: generated"},
+		{"dupl", 4, "nolint: generated"},
+		{"vet", 4, ""},
+		{"golint", 5, ""},
+		// standalone directive before declaration
+		{"errcheck", 10, "nolint"},
+		{"errcheck", 11, "nolint"},
+		{"golint", 10, ""},
+		// standalone directive before statement
+		{"golint", 17, "nolint"},
+		{"golint", 19, ""},
+		// exclude rule
+		{"gocyclo", 20, "exclude rule 1"},
+		// data races found by tests
+		{"race", 17, "nolint"},
+		{"race", 20, "exclude rule 2"},
+	}
+
+	s := newSuppressor(cfg)
+	for _, tt := range tests {
+		issue := &qfarm.Issue{Linter: &qfarm.Linter{Name: tt.linter}, Path: path, Line: tt.line}
+		got := ""
+		if s.suppress(issue) {
+			got = issue.Suppressed
+		}
+		if got != tt.want {
+			t.Errorf("%s at line %d: got suppressed %q, want %q", tt.linter, tt.line, got, tt.want)
+		}
+	}
+}
+
+// writeTempGo writes source into Go file in a new temporary directory.
+func writeTempGo(t *testing.T, src string) string {
+	dir, err := ioutil.TempDir("", "qfarm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "p.go")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
@@ -69,15 +69,28 @@ const (
 	EventTypeVetDone         = "vet-done"
 	EventTypeVetshadowDone   = "vetshadow-done"
 	EventTypeUnconvertDone   = "unconvert-done"
+	EventTypeAnalyzersDone   = "analyzers-done"
 	EventTypeMetalinterErr   = "metalinter-error"
 
 	EventTypeCoverageDone = "coverage-done"
 	EventTypeCoverageErr  = "coverage-error"
 	EventTypeError        = "error"
+	EventTypeCancelled    = "cancelled"
+	EventTypeToolchainErr = "toolchain-error"
 
 	EventTypeAlreadyAnalyzed = "already-analyzed"
 )
 
+// linterEventType returns type of the event sent when linter finishes. Custom linters send
+// "<name>-done" events.
+func linterEventType(name string) string {
+	if e, ok := linterEventsMapping[name]; ok {
+		return e
+	}
+
+	return name + "-done"
+}
+
 var linterEventsMapping = map[string]string{
 	"aligncheck":  EventTypeAligncheckDone,
 	"deadcode":    EventTypeDeadcodeDone,
@@ -0,0 +1,154 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+	"github.com/qfarm/qfarm/redis"
+)
+
+// coverageDiff compares coverage of the build with its base build. Base of a pull request is the
+// last build of the default branch, base of any other ref is the previous build of the same ref.
+// Returns nil if there is no base build or its commit can't be found in the repo.
+func (w *Worker) coverageDiff(ctx context.Context, cfg qfarm.BuildCfg, ref string, ft *FilesMap) (*qfarm.CoverageDiff, error) {
+	baseRef, baseCommit := ref, ""
+	if strings.HasPrefix(ref, "pull/") {
+		branch, err := defaultBranch(ctx, cfg.Path)
+		if err != nil {
+			warning("Can't find default branch of repo %s: %v", cfg.Repo, err)
+			return nil, nil
+		}
+
+		baseRef = branch
+		baseCommit, err = mergeBase(ctx, cfg.Path, branch)
+		if err != nil {
+			warning("Can't find merge base of %s and %s: %v", ref, branch, err)
+			return nil, nil
+		}
+	}
+
+	base, err := w.getLastBuildInfo(refBuildsKey(cfg.Repo, baseRef))
+	if err != nil && err != redis.ErrNotFound {
+		return nil, err
+	}
+
+	diff := &qfarm.CoverageDiff{BaseCommit: baseCommit}
+	if err == nil {
+		diff.BaseNo = base.No
+		diff.CoverageDelta = ft.FilesMap[ft.Root].Coverage - base.Coverage
+		if diff.BaseCommit == "" {
+			diff.BaseCommit = base.CommitHash
+		}
+	}
+	if diff.BaseCommit == "" {
+		// first build of the ref
+		return nil, nil
+	}
+
+	changed, err := changedLines(ctx, cfg.Path, diff.BaseCommit)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		warning("Can't compute patch coverage of repo %s: %v", cfg.Repo, err)
+		return nil, nil
+	}
+
+	for p, node := range ft.FilesMap {
+		if node.Dir || !strings.HasSuffix(p, ".go") {
+			continue
+		}
+
+		rel, err := filepath.Rel(ft.Root, p)
+		if err != nil {
+			continue
+		}
+
+		f := qfarm.FileCoverageDiff{Path: rel, Coverage: node.Coverage}
+		f.PatchLinesNo, f.PatchCoveredNo, f.UncoveredLines = patchCoverage(node.Blocks, changed[filepath.ToSlash(rel)])
+
+		if diff.BaseNo > 0 {
+			baseNode, err := w.getNode(cfg.Repo, diff.BaseNo, strings.TrimPrefix(p, ft.Root))
+			switch err {
+			case nil:
+				f.BaseCoverage = baseNode.Coverage
+			case redis.ErrNotFound:
+				f.New = true
+			default:
+				return nil, err
+			}
+			f.CoverageDelta = f.Coverage - f.BaseCoverage
+		}
+
+		diff.PatchLinesNo += f.PatchLinesNo
+		diff.PatchCoveredNo += f.PatchCoveredNo
+		if f.PatchLinesNo > 0 || f.CoverageDelta != 0 || f.New {
+			diff.Files = append(diff.Files, f)
+		}
+	}
+
+	if diff.PatchLinesNo > 0 {
+		diff.PatchCoverage = float64(diff.PatchCoveredNo) / float64(diff.PatchLinesNo) * 100
+	}
+	sort.Sort(byFilePath(diff.Files))
+
+	return diff, nil
+}
+
+// patchCoverage returns number of changed lines with statements, number of covered ones and
+// list of uncovered lines. Line is covered if any block containing it has been executed.
+func patchCoverage(blocks []qfarm.CoverBlock, lines []int) (total, covered int, uncovered []int) {
+	for _, line := range lines {
+		hasStmt, isCovered := false, false
+		for _, b := range blocks {
+			if b.NumStmt == 0 || line < b.Start.Line || line > b.End.Line {
+				continue
+			}
+
+			hasStmt = true
+			if b.Count > 0 {
+				isCovered = true
+				break
+			}
+		}
+
+		if !hasStmt {
+			continue
+		}
+
+		total++
+		if isCovered {
+			covered++
+		} else {
+			uncovered = append(uncovered, line)
+		}
+	}
+
+	return total, covered, uncovered
+}
+
+// getNode returns stored node of the file tree of the build.
+func (w *Worker) getNode(repo string, no int, path string) (qfarm.Node, error) {
+	var node qfarm.Node
+	data, err := w.redis.Get(fmt.Sprintf("files:%s:%d:%s", repo, no, path))
+	if err != nil {
+		return node, err
+	}
+
+	if err := json.Unmarshal(data, &node); err != nil {
+		return node, err
+	}
+
+	return node, nil
+}
+
+type byFilePath []qfarm.FileCoverageDiff
+
+func (f byFilePath) Len() int           { return len(f) }
+func (f byFilePath) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+func (f byFilePath) Less(i, j int) bool { return f[i].Path < f[j].Path }
@@ -0,0 +1,187 @@
+package worker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+)
+
+// Coverage modes supported by 'go test -covermode'.
+const (
+	coverModeSet    = "set"
+	coverModeCount  = "count"
+	coverModeAtomic = "atomic"
+)
+
+// coverProfile is a parsed coverage profile. Blocks are keyed by file name with import path of
+// its package, eg. github.com/qfarm/qfarm/worker/worker.go.
+type coverProfile struct {
+	Mode  string
+	Files map[string][]qfarm.CoverBlock
+}
+
+func newCoverProfile(mode string) *coverProfile {
+	return &coverProfile{Mode: mode, Files: make(map[string][]qfarm.CoverBlock)}
+}
+
+// parseProfile parses coverage profile written by 'go test -coverprofile'. Every line has
+// format: name.go:line.column,line.column numberOfStatements count
+func parseProfile(r io.Reader) (*coverProfile, error) {
+	p := newCoverProfile(coverModeSet)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "mode:") {
+			p.Mode = strings.TrimSpace(strings.TrimPrefix(line, "mode:"))
+			continue
+		}
+
+		i := strings.LastIndex(line, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("bad profile line: %q", line)
+		}
+		fileName := line[:i]
+
+		block := strings.Fields(line[i+1:])
+		if len(block) != 3 {
+			return nil, fmt.Errorf("bad profile line: %q", line)
+		}
+
+		curs := strings.Split(block[0], ",")
+		if len(curs) != 2 {
+			return nil, fmt.Errorf("bad curs len: %+v", curs)
+		}
+
+		start, err := parseCursor(curs[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseCursor(curs[1])
+		if err != nil {
+			return nil, err
+		}
+
+		numStmt, err := strconv.ParseInt(block[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse NumStmt: %v", err)
+		}
+		count, err := strconv.ParseInt(block[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse Count: %v", err)
+		}
+
+		p.add(fileName, qfarm.CoverBlock{Start: start, End: end, NumStmt: numStmt, Count: count})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner error while reading coverage profile file: %v", err)
+	}
+
+	return p, nil
+}
+
+func parseCursor(s string) (qfarm.Cursor, error) {
+	var c qfarm.Cursor
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 {
+		return c, fmt.Errorf("bad cursor: %q", s)
+	}
+
+	var err error
+	c.Line, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return c, fmt.Errorf("can't parse Line: %v", err)
+	}
+	c.Col, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return c, fmt.Errorf("can't parse Col: %v", err)
+	}
+
+	return c, nil
+}
+
+// add adds the block to the profile. Counts of the same block are merged: in set mode block is
+// covered if any run covered it, otherwise hit counts are summed.
+func (p *coverProfile) add(fileName string, b qfarm.CoverBlock) {
+	blocks := p.Files[fileName]
+	for i := range blocks {
+		if blocks[i].Start != b.Start || blocks[i].End != b.End {
+			continue
+		}
+
+		if p.Mode == coverModeSet {
+			if b.Count > 0 {
+				blocks[i].Count = 1
+			}
+		} else {
+			blocks[i].Count += b.Count
+		}
+		return
+	}
+
+	p.Files[fileName] = append(blocks, b)
+}
+
+// merge adds all blocks of other profile, eg. profile of tests of another package.
+func (p *coverProfile) merge(other *coverProfile) {
+	for fileName, blocks := range other.Files {
+		for _, b := range blocks {
+			p.add(fileName, b)
+		}
+	}
+}
+
+// packageFiles returns coverage reports of files of the package with given import path.
+// Files are keyed by base name.
+func (p *coverProfile) packageFiles(pkg string) map[string]qfarm.CoverFileReport {
+	files := make(map[string]qfarm.CoverFileReport)
+	for fileName, blocks := range p.Files {
+		if path.Dir(fileName) != pkg {
+			continue
+		}
+
+		sorted := append([]qfarm.CoverBlock{}, blocks...)
+		sort.Sort(byPosition(sorted))
+
+		report := qfarm.CoverFileReport{Blocks: sorted}
+		total, covered := countStmts(sorted)
+		if total > 0 {
+			report.Coverage = float64(covered) / float64(total) * 100
+		}
+		files[path.Base(fileName)] = report
+	}
+
+	return files
+}
+
+// countStmts returns number of all and covered statements in blocks.
+func countStmts(blocks []qfarm.CoverBlock) (total, covered int64) {
+	for _, b := range blocks {
+		total += b.NumStmt
+		if b.Count > 0 {
+			covered += b.NumStmt
+		}
+	}
+
+	return total, covered
+}
+
+type byPosition []qfarm.CoverBlock
+
+func (b byPosition) Len() int      { return len(b) }
+func (b byPosition) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byPosition) Less(i, j int) bool {
+	if b[i].Start.Line != b[j].Start.Line {
+		return b[i].Start.Line < b[j].Start.Line
+	}
+	return b[i].Start.Col < b[j].Start.Col
+}
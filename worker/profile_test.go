@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCoverProfileMerge(t *testing.T) {
+	tests := []struct {
+		mode     string
+		profiles []string
+
+		// counts of blocks of a.go sorted by position
+		want []int64
+	}{
+		{
+			mode: coverModeSet,
+			profiles: []string{
+				"mode: set\np/a.go:1.1,3.2 2 1\np/a.go:4.1,6.2 1 0\n",
+				"mode: set\np/a.go:1.1,3.2 2 1\np/a.go:4.1,6.2 1 1\np/a.go:7.1,8.2 1 0\n",
+			},
+			want: []int64{1, 1, 0},
+		},
+		{
+			mode: coverModeCount,
+			profiles: []string{
+				"mode: count\np/a.go:1.1,3.2 2 3\np/a.go:4.1,6.2 1 0\n",
+				"mode: count\np/a.go:1.1,3.2 2 2\np/a.go:4.1,6.2 1 5\n",
+			},
+			want: []int64{5, 5},
+		},
+		{
+			mode: coverModeAtomic,
+			profiles: []string{
+				"mode: atomic\np/a.go:4.1,6.2 1 0\np/a.go:1.1,3.2 2 7\n",
+				"mode: atomic\np/a.go:1.1,3.2 2 1\n",
+				"mode: atomic\np/b.go:1.1,3.2 2 1\n",
+			},
+			want: []int64{8, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		merged := newCoverProfile(tt.mode)
+		for _, data := range tt.profiles {
+			p, err := parseProfile(strings.NewReader(data))
+			if err != nil {
+				t.Fatalf("%s: can't parse profile %q: %v", tt.mode, data, err)
+			}
+			if p.Mode != tt.mode {
+				t.Errorf("%s: got mode %q", tt.mode, p.Mode)
+			}
+			merged.merge(p)
+		}
+
+		blocks := merged.packageFiles("p")["a.go"].Blocks
+		if len(blocks) != len(tt.want) {
+			t.Errorf("%s: got %d blocks, want %d: %+v", tt.mode, len(blocks), len(tt.want), blocks)
+			continue
+		}
+		for i, want := range tt.want {
+			if blocks[i].Count != want {
+				t.Errorf("%s: block %d: got count %d, want %d", tt.mode, i, blocks[i].Count, want)
+			}
+		}
+	}
+}
+
+func TestPackageFilesCoverage(t *testing.T) {
+	p, err := parseProfile(strings.NewReader("mode: set\np/a.go:1.1,3.2 3 1\np/a.go:4.1,6.2 1 0\np/q/b.go:1.1,2.2 1 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := p.packageFiles("p")
+	if len(files) != 1 {
+		t.Fatalf("got files %v, want only a.go", files)
+	}
+	if got := files["a.go"].Coverage; got != 75 {
+		t.Errorf("got coverage %v, want 75", got)
+	}
+}
@@ -0,0 +1,116 @@
+package worker
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+)
+
+// raceLinter is reported as a linter of data races found by race detector.
+var raceLinter = &qfarm.Linter{Name: "race", SeverityOverride: qfarm.Error}
+
+var (
+	// Write at 0x00c4200741e8 by goroutine 7:
+	raceAccess = regexp.MustCompile(`^((?:Previous )?(?:[Aa]tomic )?(?:[Rr]ead|[Ww]rite)) at 0x[0-9a-f]+ by (.+):$`)
+
+	//       /go/src/github.com/qfarm/qfarm/worker/worker.go:123 +0x3a
+	raceFrame = regexp.MustCompile(`^\s+(\S+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+type raceLocation struct {
+	access string
+	path   string
+	line   int
+}
+
+// parseRaces finds data race reports in the test output and returns them as issues. Every race
+// is reported at both racing accesses, in the innermost stack frame inside the repo.
+func parseRaces(output, repoPath string) []*qfarm.Issue {
+	var issues []*qfarm.Issue
+	var race []raceLocation
+	var current *raceLocation
+	inRace := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		switch {
+		case strings.Contains(line, "WARNING: DATA RACE"):
+			inRace, race, current = true, nil, nil
+			continue
+		case !inRace:
+			continue
+		case strings.HasPrefix(line, "=================="):
+			issues = append(issues, raceIssues(race, repoPath)...)
+			inRace, race, current = false, nil, nil
+			continue
+		}
+
+		if m := raceAccess.FindStringSubmatch(line); m != nil {
+			race = append(race, raceLocation{access: fmt.Sprintf("%s by %s", strings.ToLower(m[1]), m[2])})
+			current = &race[len(race)-1]
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" || !strings.HasPrefix(line, " ") {
+			// end of stack trace of the access
+			current = nil
+			continue
+		}
+
+		m := raceFrame.FindStringSubmatch(line)
+		if m == nil || current == nil || current.path != "" {
+			continue
+		}
+
+		if inRepo(m[1], repoPath) {
+			current.path = m[1]
+			current.line, _ = strconv.Atoi(m[2])
+		}
+	}
+
+	return issues
+}
+
+func raceIssues(race []raceLocation, repoPath string) []*qfarm.Issue {
+	var issues []*qfarm.Issue
+	for i, loc := range race {
+		if loc.path == "" {
+			continue
+		}
+
+		msg := "data race: " + loc.access
+		for j, other := range race {
+			if i == j {
+				continue
+			}
+
+			where := "outside of the repo"
+			if other.path != "" {
+				rel, _ := filepath.Rel(repoPath, other.path)
+				where = fmt.Sprintf("at %s:%d", rel, other.line)
+			}
+			msg += fmt.Sprintf(" races with %s %s", other.access, where)
+			break
+		}
+
+		issues = append(issues, &qfarm.Issue{
+			Linter:   raceLinter,
+			Severity: qfarm.Error,
+			Path:     loc.path,
+			Line:     loc.line,
+			Message:  msg,
+		})
+	}
+
+	return issues
+}
+
+func inRepo(path, repoPath string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(repoPath, "/")+"/")
+}
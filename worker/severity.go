@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/qfarm/qfarm"
+)
+
+// setSeverities overrides severities of issues as requested by repo config. The first severity
+// rule matching the issue wins over severity set for its linter. Issue reported by several
+// linters gets the highest severity set for any of them.
+func setSeverities(cfg qfarm.BuildCfg, issues []*qfarm.Issue) {
+	if len(cfg.Severities) == 0 && len(cfg.SeverityRules) == 0 {
+		return
+	}
+
+	for _, i := range issues {
+		if s, ok := linterSeverity(cfg, i); ok {
+			i.Severity = s
+		}
+
+		rel, err := filepath.Rel(cfg.Path, i.Path)
+		if err != nil {
+			rel = i.Path
+		}
+		rel = filepath.ToSlash(rel)
+
+		for _, r := range cfg.SeverityRules {
+			if matchSeverityRule(r, rel, i) {
+				i.Severity = r.Severity
+				break
+			}
+		}
+	}
+}
+
+// linterSeverity returns the highest severity set by repo config for linters which reported
+// the issue.
+func linterSeverity(cfg qfarm.BuildCfg, i *qfarm.Issue) (qfarm.Severity, bool) {
+	var severity qfarm.Severity
+	found := false
+	for _, l := range append([]string{i.Linter.String()}, i.Linters...) {
+		s, ok := cfg.Severities[l]
+		if ok && (!found || s.Rank() > severity.Rank()) {
+			severity, found = s, true
+		}
+	}
+
+	return severity, found
+}
+
+// validateSeverities checks severities and globs of severity rules of the repo config.
+func validateSeverities(cfg qfarm.BuildCfg) error {
+	for linter, s := range cfg.Severities {
+		if !s.Valid() {
+			return fmt.Errorf("invalid severity %q of linter %s, should be one of: info, warning, error", s, linter)
+		}
+	}
+
+	for n, r := range cfg.SeverityRules {
+		if !r.Severity.Valid() {
+			return fmt.Errorf("invalid severity %q of severity rule %d, should be one of: info, warning, error", r.Severity, n+1)
+		}
+		if _, err := filepath.Match(r.Path, ""); err != nil {
+			return fmt.Errorf("invalid path of severity rule %d: %v", n+1, err)
+		}
+		if len(r.Linters) == 0 && r.Path == "" {
+			return fmt.Errorf("severity rule %d should have linters or path", n+1)
+		}
+	}
+
+	return nil
+}
+
+// matchSeverityRule checks whether issue with path relative to the repo root matches the rule.
+func matchSeverityRule(r qfarm.SeverityRule, relPath string, i *qfarm.Issue) bool {
+	if r.Path != "" && !matchPath(r.Path, relPath) {
+		return false
+	}
+	if len(r.Linters) == 0 {
+		return true
+	}
+
+	for _, l := range r.Linters {
+		if reportedBy(i, l) {
+			return true
+		}
+	}
+
+	return false
+}
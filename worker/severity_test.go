@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/qfarm/qfarm"
+)
+
+func TestSetSeverities(t *testing.T) {
+	cfg := qfarm.BuildCfg{
+		Path:          "/repo",
+		Severities:    map[string]qfarm.Severity{"race": qfarm.Warning},
+		SeverityRules: []qfarm.SeverityRule{{Path: "*_test.go", Severity: qfarm.Info}},
+	}
+
+	tests := []struct {
+		linter string
+		path   string
+		want   qfarm.Severity
+	}{
+		{"race", "/repo/p.go", qfarm.Warning},
+		{"race", "/repo/q/p_test.go", qfarm.Info},
+		{"golint", "/repo/p.go", qfarm.Error},
+		{"golint", "/repo/p_test.go", qfarm.Info},
+	}
+
+	for _, tt := range tests {
+		issue := &qfarm.Issue{Linter: &qfarm.Linter{Name: tt.linter}, Severity: qfarm.Error, Path: tt.path}
+		setSeverities(cfg, []*qfarm.Issue{issue})
+		if issue.Severity != tt.want {
+			t.Errorf("%s in %s: got severity %s, want %s", tt.linter, tt.path, issue.Severity, tt.want)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Toolchain is a locally installed Go distribution.
+type Toolchain struct {
+	// Version as reported by 'go version', eg. go1.6.2
+	Version string
+
+	// GOROOT of the toolchain
+	Root string
+}
+
+// Toolchains is a set of Go toolchains available for builds.
+type Toolchains struct {
+	available []Toolchain
+	fallback  Toolchain
+}
+
+// LoadToolchains detects versions of toolchains installed in given GOROOT directories.
+// Toolchain of 'go' found in PATH is used for builds which don't request any version.
+func LoadToolchains(roots []string) (*Toolchains, error) {
+	t := &Toolchains{}
+	for _, root := range roots {
+		tc, err := detectToolchain(filepath.Join(root, "bin", "go"))
+		if err != nil {
+			return nil, err
+		}
+		t.available = append(t.available, tc)
+	}
+
+	if goBin, err := exec.LookPath("go"); err == nil {
+		tc, err := detectToolchain(goBin)
+		if err != nil {
+			return nil, err
+		}
+		t.fallback = tc
+	} else if len(t.available) > 0 {
+		t.fallback = t.available[0]
+	} else {
+		return nil, fmt.Errorf("can't find any Go toolchain")
+	}
+
+	// newest first, so the highest matching version is selected
+	sort.Sort(sort.Reverse(byVersion(t.available)))
+	return t, nil
+}
+
+// Select returns toolchain matching requested version or constraint, eg. "1.6", "1.6.2", ">=1.5",
+// ">=1.5, <1.7". Version without patch number matches all its patch releases. Empty constraint
+// selects default toolchain.
+func (t *Toolchains) Select(constraint string) (Toolchain, error) {
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" {
+		return t.fallback, nil
+	}
+
+	candidates := append(append([]Toolchain{}, t.available...), t.fallback)
+	for _, tc := range candidates {
+		ok, err := versionMatches(tc.Version, constraint)
+		if err != nil {
+			return Toolchain{}, err
+		}
+		if ok {
+			return tc, nil
+		}
+	}
+
+	versions := make([]string, 0, len(candidates))
+	for _, tc := range candidates {
+		versions = append(versions, tc.Version)
+	}
+	return Toolchain{}, fmt.Errorf("no Go toolchain matching %q, available: %s", constraint, strings.Join(versions, ", "))
+}
+
+// detectToolchain runs 'go version' and 'go env GOROOT' of the go binary.
+func detectToolchain(goBin string) (Toolchain, error) {
+	out, err := exec.Command(goBin, "version").Output()
+	if err != nil {
+		return Toolchain{}, fmt.Errorf("can't detect version of %s: %v", goBin, err)
+	}
+
+	// go version go1.6.2 linux/amd64
+	fields := strings.Fields(string(out))
+	if len(fields) < 3 {
+		return Toolchain{}, fmt.Errorf("can't parse version of %s: %q", goBin, out)
+	}
+
+	root, err := exec.Command(goBin, "env", "GOROOT").Output()
+	if err != nil {
+		return Toolchain{}, fmt.Errorf("can't detect GOROOT of %s: %v", goBin, err)
+	}
+
+	return Toolchain{Version: fields[2], Root: strings.TrimSpace(string(root))}, nil
+}
+
+// versionMatches checks whether Go version (eg. go1.6.2) satisfies all comma separated constraints.
+func versionMatches(version, constraint string) (bool, error) {
+	v := parseVersion(version)
+	for _, c := range strings.Split(constraint, ",") {
+		c = strings.TrimSpace(c)
+		op := strings.TrimRight(c[:len(c)-len(strings.TrimLeft(c, "<>=!~"))], " ")
+		want := strings.TrimSpace(c[len(op):])
+		if want == "" {
+			return false, fmt.Errorf("invalid Go version constraint %q", constraint)
+		}
+		w := parseVersion(want)
+
+		var ok bool
+		switch op {
+		case "", "=", "==":
+			ok = w.prefixOf(v)
+		case "!=":
+			ok = !w.prefixOf(v)
+		case ">":
+			ok = v.compare(w) > 0 && !w.prefixOf(v)
+		case ">=":
+			ok = v.compare(w) >= 0 || w.prefixOf(v)
+		case "<":
+			ok = v.compare(w) < 0 && !w.prefixOf(v)
+		case "<=":
+			ok = v.compare(w) <= 0 || w.prefixOf(v)
+		case "~":
+			// same minor release
+			ok = v.compare(w) >= 0 && len(v.parts) > 1 && len(w.parts) > 1 && v.parts[0] == w.parts[0] && v.parts[1] == w.parts[1]
+		default:
+			return false, fmt.Errorf("invalid Go version constraint %q", constraint)
+		}
+
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+type goVersion struct {
+	parts []int
+	pre   string
+}
+
+// parseVersion parses versions like go1.6, 1.6.2 or go1.7rc1.
+func parseVersion(s string) goVersion {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "go")
+	v := goVersion{}
+	for i, p := range strings.Split(s, ".") {
+		n := strings.IndexFunc(p, func(r rune) bool { return r < '0' || r > '9' })
+		if n >= 0 {
+			v.pre = p[n:]
+			p = p[:n]
+		}
+		num, _ := strconv.Atoi(p)
+		v.parts = append(v.parts, num)
+		if n >= 0 || i >= 2 {
+			break
+		}
+	}
+
+	return v
+}
+
+// prefixOf checks whether v matches all parts of other, eg. 1.6 is a prefix of 1.6.2.
+func (v goVersion) prefixOf(other goVersion) bool {
+	if len(v.parts) > len(other.parts) {
+		return false
+	}
+	for i := range v.parts {
+		if v.parts[i] != other.parts[i] {
+			return false
+		}
+	}
+
+	return v.pre == "" || v.pre == other.pre
+}
+
+func (v goVersion) compare(other goVersion) int {
+	for i := 0; i < 3; i++ {
+		a, b := v.part(i), other.part(i)
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	// pre-releases come before the release
+	switch {
+	case v.pre == other.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case other.pre == "":
+		return -1
+	case v.pre < other.pre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func (v goVersion) part(i int) int {
+	if i < len(v.parts) {
+		return v.parts[i]
+	}
+	return 0
+}
+
+type byVersion []Toolchain
+
+func (t byVersion) Len() int      { return len(t) }
+func (t byVersion) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t byVersion) Less(i, j int) bool {
+	return parseVersion(t[i].Version).compare(parseVersion(t[j].Version)) < 0
+}
+
+// lookPath searches for an executable in directories listed in PATH of env.
+// If env is empty, worker's PATH is used.
+func lookPath(file string, env []string) (string, error) {
+	if env == nil || strings.Contains(file, "/") {
+		return exec.LookPath(file)
+	}
+
+	for i := len(env) - 1; i >= 0; i-- {
+		if !strings.HasPrefix(env[i], "PATH=") {
+			continue
+		}
+
+		for _, dir := range filepath.SplitList(strings.TrimPrefix(env[i], "PATH=")) {
+			if dir == "" {
+				dir = "."
+			}
+			path := filepath.Join(dir, file)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+				return path, nil
+			}
+		}
+		break
+	}
+
+	return "", fmt.Errorf("executable file %s not found in $PATH", file)
+}
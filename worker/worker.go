@@ -1,30 +1,51 @@
 package worker
 
 import (
-	"fmt"
-
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/qfarm/qfarm"
+	"github.com/qfarm/qfarm/queue"
 	"github.com/qfarm/qfarm/redis"
 )
 
 type Worker struct {
-	linter   *Metalinter
-	redis    *redis.Service
-	notifier *Notifier
-	coverage *CoverageChecker
-	config   *Cfg
+	id         string
+	linter     *Metalinter
+	redis      *redis.Service
+	queue      *queue.Queue
+	notifier   *Notifier
+	coverage   *CoverageChecker
+	toolchains *Toolchains
+	config     *Cfg
+
+	// cancel functions of jobs in progress
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// ErrAlreadyAnalyzed is returned when the last commit of the repo has been already analyzed.
+var ErrAlreadyAnalyzed = errors.New("repo already analyzed")
+
+// permanentError is an analysis error which won't go away when the build is retried.
+type permanentError struct {
+	error
 }
 
+// popTimeout is a maximum time of waiting for a new job in the queue.
+const popTimeout = 5 * time.Second
+
 func NewWorker(config *Cfg) (*Worker, error) {
-	w := &Worker{config: config}
+	w := &Worker{config: config, cancels: make(map[string]context.CancelFunc)}
 	cfg := redis.NewConfig().WithConnection(config.RedisConn).WithPassword(config.RedisPass)
 	var err error
 	w.redis, err = redis.NewService(cfg)
@@ -32,41 +53,225 @@ func NewWorker(config *Cfg) (*Worker, error) {
 		return nil, fmt.Errorf("Can't create the redis service: %v\n", err)
 	}
 
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("Can't get hostname: %v\n", err)
+	}
+	w.id = fmt.Sprintf("%s-%d", host, os.Getpid())
+
+	w.queue = queue.New(w.redis, queue.BuildQueue)
+	w.queue.VisibilityTimeout = time.Duration(config.QueueVisibilityTimeout) * time.Second
+	w.queue.MaxRetries = config.QueueMaxRetries
+
+	w.toolchains, err = LoadToolchains(config.Toolchains)
+	if err != nil {
+		return nil, fmt.Errorf("Can't load Go toolchains: %v\n", err)
+	}
+
 	w.notifier = NewNotifier(w.redis)
 	w.linter = NewMetalinter(config, w.redis, w.notifier)
-	w.coverage = NewCoverageChecker(config, w.notifier)
+	w.coverage = NewCoverageChecker(config, w.redis, w.notifier)
 
 	return w, nil
 }
 
+// Run processes jobs from the build queue. Jobs waiting in the queue are processed
+// right away and then worker waits for the new ones.
 func (w *Worker) Run() error {
-	if err := w.redis.Subscribe("test-q-channel", w.fetchAndAnalyze); err != nil {
+	if err := w.queue.Heartbeat(w.id); err != nil {
 		return err
 	}
 
+	go w.keepAlive()
+	go w.listenForCancels()
+
+	for {
+		d, err := w.queue.Pop(w.id, popTimeout)
+		if err == redis.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		w.process(d)
+	}
+}
+
+// Stop releases jobs of the worker, so other workers can take them over right away
+// instead of waiting for the visibility timeout.
+func (w *Worker) Stop() error {
+	return w.queue.Stop(w.id)
+}
+
+// keepAlive refreshes worker heartbeat and requeues jobs of dead workers.
+func (w *Worker) keepAlive() {
+	interval := w.queue.VisibilityTimeout / 3
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := w.queue.Heartbeat(w.id); err != nil {
+			log.Printf("Can't send heartbeat! Err: %v \n", err)
+		}
+
+		if _, err := w.queue.Requeue(); err != nil {
+			log.Printf("Can't requeue jobs of dead workers! Err: %v \n", err)
+		}
+	}
+}
+
+// listenForCancels subscribes to the worker cancel channel and aborts cancelled jobs.
+func (w *Worker) listenForCancels() {
+	for {
+		if err := w.redis.Subscribe(queue.CancelChannel(w.id), w.cancelJob); err != nil {
+			log.Printf("Error while listening for cancelled jobs! Err: %v \n", err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (w *Worker) cancelJob(data interface{}) error {
+	msg, ok := data.([]interface{})
+	if !ok || len(msg) != 3 {
+		return nil
+	}
+
+	kind, _ := msg[0].([]byte)
+	id, _ := msg[2].([]byte)
+	if string(kind) != "message" {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if cancel, ok := w.cancels[string(id)]; ok {
+		log.Printf("Cancelling job %s", id)
+		cancel()
+	}
+
 	return nil
 }
 
-func (w *Worker) fetchAndAnalyze(data interface{}) error {
-	elem, err := w.redis.ListPop("test-q-list") // TODO: drain list to the bottom
+func (w *Worker) process(d *queue.Delivery) {
+	job := &d.Job
+	log.Printf("Processing job %s (repo %s, attempt %d)", job.ID, job.Repo, d.Attempt)
+
+	_, buildTimeout := w.config.Timeouts(qfarm.BuildCfg{})
+	ctx, cancel := context.WithTimeout(context.Background(), buildTimeout)
+	w.mu.Lock()
+	w.cancels[job.ID] = cancel
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		delete(w.cancels, job.ID)
+		w.mu.Unlock()
+		cancel()
+	}()
+
+	err := w.claim(job)
+	if err == nil {
+		err = w.analyze(ctx, job)
+	}
+	if err == nil || err == ErrAlreadyAnalyzed {
+		w.setJobState(job, qfarm.JobDone)
+		if err := d.Ack(); err != nil {
+			log.Printf("Can't acknowledge job %s! Err: %v \n", job.ID, err)
+		}
+		return
+	}
+
+	if err == context.Canceled {
+		w.notifier.SendEvent(job.Repo, fmt.Sprintf("Build of repo %s cancelled", job.Repo), EventTypeCancelled)
+		w.setJobState(job, qfarm.JobCancelled)
+		if err := d.Ack(); err != nil {
+			log.Printf("Can't acknowledge job %s! Err: %v \n", job.ID, err)
+		}
+		return
+	}
+
+	if err == context.DeadlineExceeded {
+		err = permanentError{errors.New("build exceeded time limit")}
+	}
+
+	if _, ok := err.(permanentError); ok {
+		w.notifier.SendEvent(job.Repo, fmt.Sprintf("Error: %s", err.Error()), EventTypeError)
+		job.Error = err.Error()
+		w.setJobState(job, qfarm.JobFailed)
+		if err := d.Reject(); err != nil {
+			log.Printf("Can't reject job %s! Err: %v \n", job.ID, err)
+		}
+		return
+	}
+
+	w.notifier.SendEvent(job.Repo, fmt.Sprintf("Error: %s", err.Error()), EventTypeError)
+	log.Printf("Error during worker analysis! Err: %v \n", err)
+
+	job.Error = err.Error()
+	if d.Attempt >= w.queue.MaxRetries {
+		w.setJobState(job, qfarm.JobFailed)
+	} else {
+		w.setJobState(job, qfarm.JobQueued)
+	}
+
+	if err := d.Retry(); err != nil {
+		log.Printf("Can't retry job %s! Err: %v \n", job.ID, err)
+	}
+}
+
+// claim assigns the job to the worker before it's processed. Once the job is claimed, it can
+// be cancelled only by notifying the worker. Returns context.Canceled if the job has been
+// cancelled already.
+func (w *Worker) claim(job *qfarm.Job) error {
+	claimed, err := queue.ClaimJob(w.redis, job, w.id, qfarm.JobDownloading)
 	if err != nil {
-		// do nothing other worker might got the value from list before
-		return nil
+		return err
+	}
+	if !claimed {
+		return context.Canceled
 	}
 
-	if err := w.analyze(string(elem.([]byte))); err != nil {
-		w.notifier.SendEvent(string(elem.([]byte)), fmt.Sprintf("Error: %s", err.Error()), EventTypeError)
-		log.Printf("Error during worker analysis! Err: %v \n", err)
+	// cancel might have been requested before the claim, while the job was still queued
+	cancelled, err := queue.JobCancelled(w.redis, job.ID)
+	if err != nil {
+		return err
+	}
+	if cancelled {
+		return context.Canceled
 	}
 
 	return nil
 }
 
-func (w *Worker) analyze(repo string) error {
+// setJobState updates and stores state of the job.
+func (w *Worker) setJobState(job *qfarm.Job, state qfarm.JobState) {
+	job.State = state
+	if err := queue.SaveJob(w.redis, job); err != nil {
+		log.Printf("Can't store state of job %s! Err: %v \n", job.ID, err)
+	}
+}
+
+func (w *Worker) analyze(ctx context.Context, job *qfarm.Job) error {
 	start := time.Now()
+	repo := job.Repo
+
+	// prepare isolated workspace
+	ws, err := NewWorkspace(w.config.WorkspaceDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := ws.Remove(); err != nil {
+			log.Printf("Can't remove workspace %s! Err: %v \n", ws.Root, err)
+		}
+	}()
 
 	// download repo
-	if err := w.download(repo); err != nil {
+	ref, err := w.download(ctx, ws, repo, job.Ref, job.Commit)
+	if err != nil {
 		return err
 	}
 
@@ -74,12 +279,24 @@ func (w *Worker) analyze(repo string) error {
 		return err
 	}
 
-	lastCommitHash, err := lastCommitHash(repo)
+	lastCommitHash, err := lastCommitHash(ws.RepoPath(repo))
 	if err != nil {
 		return err
 	}
 
-	log.Printf("Hash of last commit %s", lastCommitHash)
+	log.Printf("Hash of last commit %s (%s)", lastCommitHash, ref)
+
+	// coverage might be uploaded for commits whose tests can't be run on the worker
+	upload, err := w.coverageUpload(repo, lastCommitHash)
+	if err != nil {
+		return err
+	}
+
+	// as well as results of linters run outside of qfarm
+	issueUploads, err := w.issuesUploads(repo, lastCommitHash)
+	if err != nil {
+		return err
+	}
 
 	// get last build number
 	firstTimeBuild := false
@@ -92,25 +309,59 @@ func (w *Worker) analyze(repo string) error {
 		}
 	}
 
-	if !firstTimeBuild && w.config.CheckLastCommitHash {
-		// someone wants to analyze the same repo twice
-		if buildInfo.CommitHash == lastCommitHash {
-			w.notifier.SendEventWithPayload(repo, fmt.Sprintf("Repo %s already analyzed!", repo), EventTypeAlreadyAnalyzed, fmt.Sprintf("%s", buildInfo.No))
-			return fmt.Errorf("repo %s already analyzed!", repo)
+	if !firstTimeBuild && w.config.CheckLastCommitHash && upload == nil && len(issueUploads) == 0 {
+		// someone wants to analyze the same ref twice
+		refBuildInfo, err := w.getLastBuildInfo(refBuildsKey(repo, ref))
+		if err != nil && err != redis.ErrNotFound {
+			return err
+		}
+		if err == nil && refBuildInfo.CommitHash == lastCommitHash {
+			w.notifier.SendEventWithPayload(repo, fmt.Sprintf("Repo %s already analyzed!", repo), EventTypeAlreadyAnalyzed, fmt.Sprintf("%d", refBuildInfo.No))
+			job.BuildNo = refBuildInfo.No
+			return ErrAlreadyAnalyzed
 		}
 	}
 
 	// generate new build no
-	newBuild := qfarm.Build{Repo: repo, CommitHash: lastCommitHash, Time: time.Now().UTC()}
+	newBuild := qfarm.Build{Repo: repo, CommitHash: lastCommitHash, Ref: ref, Time: time.Now().UTC()}
 	if firstTimeBuild {
 		newBuild.No = 1
 	} else {
 		newBuild.No = buildInfo.No + 1
 	}
+	job.BuildNo = newBuild.No
 
 	// create repo config
-	buildCfg, err := LoadRepoCfg(repo, path.Join(os.Getenv("GOPATH"), "src", repo))
+	buildCfg, err := LoadRepoCfg(repo, ws.RepoPath(repo))
+	if err != nil {
+		return err
+	}
+	buildCfg.Workspace = ws.Root
+	buildCfg.Ref = ref
+
+	// repo config might shorten build time limit
+	_, buildTimeout := w.config.Timeouts(*buildCfg)
+	ctx, cancel := context.WithDeadline(ctx, start.Add(buildTimeout))
+	defer cancel()
+
+	buildCfg.Modules, err = FindModules(buildCfg.Path)
+	if err != nil {
+		return err
+	}
+
+	// select Go toolchain requested by repo config
+	toolchain, err := w.toolchains.Select(toolchainConstraint(*buildCfg))
 	if err != nil {
+		if len(buildCfg.Modules) == 0 {
+			err = fmt.Errorf("repo without go.mod needs Go toolchain older than %s ('go get' in GOPATH mode has been removed): %v", gopathGetRemoved, err)
+		}
+		w.notifier.SendEvent(repo, fmt.Sprintf("Go toolchain error: %s", err.Error()), EventTypeToolchainErr)
+		return permanentError{err}
+	}
+	buildCfg.GoRoot = toolchain.Root
+	log.Printf("Using Go toolchain %s (%s)", toolchain.Version, toolchain.Root)
+
+	if err := w.fetchDependencies(ctx, *buildCfg); err != nil {
 		return err
 	}
 	newBuild.Config = *buildCfg
@@ -122,40 +373,93 @@ func (w *Worker) analyze(repo string) error {
 	}
 
 	// run all linters
-	if err := w.linter.Start(*buildCfg, newBuild.No, ft); err != nil {
+	w.setJobState(job, qfarm.JobLinting)
+	issues, timedOutLinters, err := w.linter.Start(ctx, *buildCfg)
+	if err != nil {
 		return err
 	}
+	issues = append(issues, w.linter.Import(*buildCfg, issueUploads)...)
+
+	// in baseline mode only new issues are scored
+	var base *qfarm.Baseline
+	if buildCfg.Baseline {
+		base, err = w.baseline(repo)
+		if err != nil {
+			return err
+		}
+	}
 
 	// run coverage
-	if err := w.coverage.Start(*buildCfg, ft); err != nil {
+	w.setJobState(job, qfarm.JobCoverage)
+	coverReport, err := w.coverage.Start(ctx, *buildCfg, newBuild.No, ft, upload)
+	if err != nil {
+		return err
+	}
+
+	// data races are reported as issues, so they're suppressed and scored like issues of linters
+	if coverReport != nil {
+		issues = append(issues, coverReport.Races...)
+	}
+
+	sup := newSuppressor(*buildCfg)
+	baselineDiff, err := w.linter.Store(*buildCfg, newBuild.No, ft, sup, base, issues)
+	if err != nil {
 		return err
 	}
 
+	// compare coverage with base build
+	coverDiff, err := w.coverageDiff(ctx, *buildCfg, ref, ft)
+	if err != nil {
+		return err
+	}
+
+	w.setJobState(job, qfarm.JobStoring)
 	if err := w.storeNodes(buildCfg.Repo, newBuild.No, ft); err != nil {
 		return fmt.Errorf("can't store nodes in Redis: %v", err)
 	}
 
+	if err := w.storeTests(buildCfg.Repo, newBuild.No, coverReport); err != nil {
+		return fmt.Errorf("can't store tests in Redis: %v", err)
+	}
+
 	root, ok := ft.FilesMap[ft.Root]
 	if !ok {
 		return fmt.Errorf("Can't find root!")
 	}
 
+	scored := *root
+	if baselineDiff != nil {
+		scored.ErrorsNo, scored.WarningsNo = baselineDiff.NewErrorsNo, baselineDiff.NewWarningsNo
+	}
+
 	// generate report
+	flaky := flakyTests(coverReport)
 	r := qfarm.Report{
 		Repo:              newBuild.Repo,
 		No:                newBuild.No,
-		Score:             calculateScore(root),
+		Score:             calculateScore(&scored),
 		Time:              qfarm.JSONTime(start),
 		Took:              time.Now().Sub(start).String(),
 		CommitHash:        newBuild.CommitHash,
+		Ref:               newBuild.Ref,
+		Toolchain:         toolchain.Version,
 		Config:            newBuild.Config,
+		TimedOutLinters:   timedOutLinters,
+		FlakyTests:        flaky,
+		CoverageDiff:      coverDiff,
+		CoverageUploaded:  upload != nil && coverReport != nil,
+		Baseline:          baselineDiff,
+		Modules:           moduleReports(*buildCfg, ft, coverReport),
 		Coverage:          root.Coverage,
 		TestsNo:           root.TestsNo,
 		FailedNo:          root.FailedNo,
 		PassedNo:          root.PassedNo,
+		FlakyNo:           len(flaky),
 		IssuesNo:          root.IssuesNo,
 		ErrorsNo:          root.ErrorsNo,
 		WarningsNo:        root.WarningsNo,
+		InfosNo:           root.InfosNo,
+		SuppressedNo:      sup.count,
 		TechnicalDeptCost: root.WarningsNo*CostOfWarning + root.ErrorsNo*CostOfError,
 		TechnicalDeptTime: (time.Duration(root.ErrorsNo*FixTimeOfError)*time.Minute + time.Duration(root.WarningsNo*FixTimeOfWarning)*time.Minute).String(),
 	}
@@ -176,10 +480,18 @@ func (w *Worker) analyze(repo string) error {
 		return err
 	}
 
+	// add new build to list of builds per branch, tag or pull request
+	if err := w.redis.ListPush("builds:"+refBuildsKey(repo, ref), rData); err != nil {
+		return err
+	}
+
 	if err := w.redis.Set(fmt.Sprintf("reports:%s:%d", newBuild.Repo, newBuild.No), -1, rData); err != nil {
 		return err
 	}
 
+	job.Report = &r
+	job.Error = ""
+
 	w.notifier.SendEventWithPayload(repo, "All tasks done!", EventTypeAllDone, fmt.Sprintf("%d", newBuild.No))
 
 	fmt.Printf("All done\n")
@@ -245,9 +557,85 @@ func (w *Worker) storeNodes(repo string, no int, ft *FilesMap) error {
 	return nil
 }
 
-func (w *Worker) getLastBuildInfo(repo string) (qfarm.Report, error) {
+// storeTests stores results of all tests of the build. Tests are kept in sorted sets ranked
+// by duration and their output is stored separately.
+func (w *Worker) storeTests(repo string, no int, r *qfarm.CoverageReport) error {
+	if r == nil {
+		return nil
+	}
+
+	for _, pkg := range r.Packages {
+		for _, t := range pkg.Tests {
+			if err := w.redis.Set(qfarm.TestOutputKey(repo, no, t.Package, t.Name), -1, t.Output); err != nil {
+				return err
+			}
+
+			t.Output = ""
+			data, err := json.Marshal(t)
+			if err != nil {
+				return err
+			}
+
+			rank := int(t.Time / time.Millisecond)
+			if _, err := w.redis.SortedSetAdd(fmt.Sprintf("tests:%s:%d", repo, no), data, rank); err != nil {
+				return err
+			}
+
+			if _, err := w.redis.SortedSetAdd(fmt.Sprintf("tests:%s:%d:%s", repo, no, t.Status), data, rank); err != nil {
+				return err
+			}
+
+			if t.Flaky {
+				if _, err := w.redis.SortedSetAdd(fmt.Sprintf("tests:%s:%d:flaky", repo, no), data, rank); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// coverageUpload returns coverage uploaded for the commit or nil if there is none.
+func (w *Worker) coverageUpload(repo, commit string) (*qfarm.CoverageUpload, error) {
+	data, err := w.redis.Get(fmt.Sprintf("coverage-uploads:%s:%s", repo, commit))
+	if err != nil {
+		if err == redis.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var upload qfarm.CoverageUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, fmt.Errorf("can't decode coverage upload: %v", err)
+	}
+
+	return &upload, nil
+}
+
+// issuesUploads returns results of linters uploaded for the commit.
+func (w *Worker) issuesUploads(repo, commit string) ([]qfarm.IssuesUpload, error) {
+	data, err := w.redis.ListGetAllElements(fmt.Sprintf("issues-uploads:%s:%s", repo, commit))
+	if err != nil && err != redis.ErrNotFound {
+		return nil, err
+	}
+
+	uploads := make([]qfarm.IssuesUpload, 0, len(data))
+	for _, d := range data {
+		var u qfarm.IssuesUpload
+		if err := json.Unmarshal(d, &u); err != nil {
+			return nil, fmt.Errorf("can't decode issues upload: %v", err)
+		}
+		uploads = append(uploads, u)
+	}
+
+	return uploads, nil
+}
+
+func (w *Worker) getLastBuildInfo(key string) (qfarm.Report, error) {
 	var build qfarm.Report
-	data, err := w.redis.ListGetLast("builds:" + repo)
+	data, err := w.redis.ListGetLast("builds:" + key)
 	if err != nil {
 		return build, err
 	}
@@ -259,19 +647,105 @@ func (w *Worker) getLastBuildInfo(repo string) (qfarm.Report, error) {
 	return build, nil
 }
 
-func (w *Worker) download(repo string) error {
+// download clones the repo into the workspace and checks out requested ref. If commit is given,
+// it's checked out instead of the head of the ref. If both are empty, default branch is used.
+// Returns name of analyzed ref.
+func (w *Worker) download(ctx context.Context, ws *Workspace, repo, ref, commit string) (string, error) {
 	fmt.Printf("Downloading %s...\n", repo)
-	if err := exec.Command("go", "get", "-u", "-t", path.Join(repo, "...")).Run(); err != nil {
-		return err
+	repoPath := ws.RepoPath(repo)
+	if err := os.MkdirAll(path.Dir(repoPath), 0755); err != nil {
+		return "", err
+	}
+
+	if err := git(ctx, ws.Root, "clone", "--quiet", "https://"+repo, repoPath); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+
+	ref = qfarm.NormalizeRef(ref)
+	if ref == "" && commit == "" {
+		branch, err := currentBranch(ctx, repoPath)
+		if err != nil {
+			return "", err
+		}
+		return branch, nil
 	}
 
-	fmt.Printf("Repo %s downloaded!\n", repo)
+	if ref != "" {
+		// with commit given, ref is fetched only to make its commits (eg. of pull request) available
+		if err := checkoutRef(ctx, repoPath, ref); err != nil && (commit == "" || ctx.Err() != nil) {
+			return "", err
+		}
+	}
 
-	w.notifier.SendEvent(repo, fmt.Sprintf("Repo %s downloaded", repo), EventTypeDownloadDone)
+	if commit == "" {
+		return ref, nil
+	}
+
+	if err := checkoutRef(ctx, repoPath, commit); err != nil {
+		return "", err
+	}
+
+	if ref == "" {
+		return commit, nil
+	}
+	return ref, nil
+}
+
+// gopathGetRemoved is the first Go release without 'go get' in GOPATH mode.
+const gopathGetRemoved = "1.22"
+
+// toolchainConstraint returns Go version constraint of the build. Dependencies of GOPATH based
+// repos are fetched with 'go get', so they need toolchain which still supports it.
+func toolchainConstraint(cfg qfarm.BuildCfg) string {
+	if len(cfg.Modules) > 0 {
+		return cfg.Go
+	}
+
+	if strings.TrimSpace(cfg.Go) == "" {
+		return "<" + gopathGetRemoved
+	}
+	return cfg.Go + ", <" + gopathGetRemoved
+}
+
+// fetchDependencies downloads dependencies of every module into the workspace module cache.
+// Dependencies of GOPATH based repos are downloaded into the workspace GOPATH.
+func (w *Worker) fetchDependencies(ctx context.Context, cfg qfarm.BuildCfg) error {
+	cmds := make([]*exec.Cmd, 0)
+	if len(cfg.Modules) == 0 {
+		cmd := goCommand(cfg, "get", "-d", "-t", "./...")
+		cmd.Dir = cfg.Path
+		cmds = append(cmds, cmd)
+	}
+	for _, m := range cfg.Modules {
+		cmd := goCommand(cfg, "mod", "download")
+		cmd.Dir = path.Join(cfg.Path, m.Dir)
+		cmds = append(cmds, cmd)
+	}
+
+	for _, cmd := range cmds {
+		if err := runCommand(ctx, cmd); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("can't download dependencies in %s: %v", cmd.Dir, err)
+		}
+	}
+
+	fmt.Printf("Repo %s downloaded!\n", cfg.Repo)
+
+	w.notifier.SendEvent(cfg.Repo, fmt.Sprintf("Repo %s downloaded", cfg.Repo), EventTypeDownloadDone)
 
 	return nil
 }
 
+// refBuildsKey returns suffix of the key with list of builds of the ref.
+func refBuildsKey(repo, ref string) string {
+	return repo + ":" + ref
+}
+
 func (w *Worker) markAsUserRepo(repo string) error {
 	userName := strings.Split(repo, "/")[1]
 	_, err := w.redis.SortedSetRank("users:"+userName+":repos", repo)
@@ -279,9 +753,7 @@ func (w *Worker) markAsUserRepo(repo string) error {
 	return err
 }
 
-func lastCommitHash(repo string) (string, error) {
-	repoPath := path.Join(os.Getenv("GOPATH"), "src", repo)
-
+func lastCommitHash(repoPath string) (string, error) {
 	cmd := exec.Command("git", "rev-parse", "HEAD")
 	cmd.Dir = repoPath
 
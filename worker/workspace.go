@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/qfarm/qfarm"
+)
+
+// Workspace is a temporary directory in which single build is done. It holds private GOPATH
+// with the repo checkout and all of its dependencies, so concurrent builds never share any files.
+type Workspace struct {
+	Root string
+}
+
+// NewWorkspace creates new empty workspace inside the dir. If dir is empty, default
+// directory for temporary files is used.
+func NewWorkspace(dir string) (*Workspace, error) {
+	root, err := ioutil.TempDir(dir, "qfarm-")
+	if err != nil {
+		return nil, fmt.Errorf("can't create workspace: %v", err)
+	}
+
+	ws := &Workspace{Root: root}
+	for _, d := range []string{ws.GOPATH(), ws.cacheDir()} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			ws.Remove()
+			return nil, fmt.Errorf("can't create workspace: %v", err)
+		}
+	}
+
+	return ws, nil
+}
+
+// GOPATH returns path of the workspace private GOPATH.
+func (ws *Workspace) GOPATH() string {
+	return filepath.Join(ws.Root, "gopath")
+}
+
+// RepoPath returns path of the repo checkout inside the workspace.
+func (ws *Workspace) RepoPath(repo string) string {
+	return filepath.Join(ws.GOPATH(), "src", repo)
+}
+
+func (ws *Workspace) cacheDir() string {
+	return filepath.Join(ws.Root, "cache")
+}
+
+// Remove deletes the workspace with all its content.
+func (ws *Workspace) Remove() error {
+	return os.RemoveAll(ws.Root)
+}
+
+// buildEnv returns environment of the commands run during the build. Commands run inside
+// the workspace with module cache kept in its private GOPATH and use selected Go toolchain.
+// Empty workspace means that commands use worker's environment.
+func buildEnv(cfg qfarm.BuildCfg) []string {
+	if cfg.Workspace == "" {
+		return nil
+	}
+
+	goModules := "GO111MODULE=off"
+	if len(cfg.Modules) > 0 {
+		goModules = "GO111MODULE=on"
+	}
+
+	ws := &Workspace{Root: cfg.Workspace}
+	vars := []string{
+		"GOPATH=" + ws.GOPATH(),
+		"GOCACHE=" + filepath.Join(ws.cacheDir(), "build"),
+		goModules,
+	}
+	if cfg.GoRoot != "" {
+		vars = append(vars,
+			"GOROOT="+cfg.GoRoot,
+			"PATH="+filepath.Join(cfg.GoRoot, "bin")+string(filepath.ListSeparator)+os.Getenv("PATH"),
+			// never let go command switch to another toolchain
+			"GOTOOLCHAIN=local",
+		)
+	}
+
+	return setEnv(os.Environ(), vars...)
+}
+
+// goCommand returns go command of the toolchain selected for the build.
+func goCommand(cfg qfarm.BuildCfg, args ...string) *exec.Cmd {
+	goBin := "go"
+	if cfg.GoRoot != "" {
+		goBin = filepath.Join(cfg.GoRoot, "bin", "go")
+	}
+
+	cmd := exec.Command(goBin, args...)
+	cmd.Env = buildEnv(cfg)
+	return cmd
+}
+
+// tempDir returns directory for temporary files of the build.
+func tempDir(workspace string) string {
+	if workspace == "" {
+		return os.TempDir()
+	}
+
+	return workspace
+}
+
+// setEnv returns copy of env with given variables overridden.
+func setEnv(env []string, vars ...string) []string {
+	out := make([]string, 0, len(env)+len(vars))
+	for _, e := range env {
+		overridden := false
+		for _, v := range vars {
+			if strings.HasPrefix(e, v[:strings.Index(v, "=")+1]) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			out = append(out, e)
+		}
+	}
+
+	return append(out, vars...)
+}